@@ -0,0 +1,310 @@
+// Package config implements the declarative probe configuration of
+// net-exporter, which is read from a YAML or JSON file.
+package config
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/net-exporter/dns"
+	"github.com/giantswarm/net-exporter/http"
+	"github.com/giantswarm/net-exporter/network"
+	"github.com/giantswarm/net-exporter/ntp"
+)
+
+const (
+	defaultDNSPort = 53
+)
+
+// Config describes the DNS, HTTP, network and NTP targets to probe.
+type Config struct {
+	DNS     DNS     `json:"dns"`
+	HTTP    HTTP    `json:"http"`
+	Network Network `json:"network"`
+	NTP     NTP     `json:"ntp"`
+}
+
+// DNS configures the DNS collector. PerPod queries each Pod of the DNS
+// service instead of the service itself, and NodeLocal additionally queries a
+// node-local DNS cache. Interval, Timeout, Protocols and QueryTypes are used
+// for targets which do not set their own.
+type DNS struct {
+	Namespace  string          `json:"namespace,omitempty"`
+	Service    string          `json:"service,omitempty"`
+	PerPod     bool            `json:"perPod,omitempty"`
+	NodeLocal  DNSNodeLocal    `json:"nodeLocal,omitempty"`
+	Interval   metav1.Duration `json:"interval,omitempty"`
+	Timeout    metav1.Duration `json:"timeout,omitempty"`
+	Protocols  []string        `json:"protocols,omitempty"`
+	QueryTypes []string        `json:"queryTypes,omitempty"`
+	Targets    []DNSTarget     `json:"targets,omitempty"`
+}
+
+// DNSNodeLocal locates a node-local DNS cache, e.g. NodeLocal DNSCache. It is
+// disabled if Address is empty.
+type DNSNodeLocal struct {
+	// Address is the IP the cache listens on, e.g. the link-local
+	// 169.254.20.10 or the IP of the node.
+	Address string `json:"address,omitempty"`
+	// Port defaults to 53.
+	Port int `json:"port,omitempty"`
+}
+
+// DNSTarget is a host resolved by the DNS collector.
+type DNSTarget struct {
+	Host       string            `json:"host"`
+	Interval   metav1.Duration   `json:"interval,omitempty"`
+	Timeout    metav1.Duration   `json:"timeout,omitempty"`
+	Protocols  []string          `json:"protocols,omitempty"`
+	QueryTypes []string          `json:"queryTypes,omitempty"`
+	Expect     DNSExpectation    `json:"expect,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+}
+
+// DNSExpectation describes the answers a DNS target is expected to resolve
+// to. Answers not matching it are counted, but still count as resolved.
+type DNSExpectation struct {
+	IPs    []string        `json:"ips,omitempty"`
+	CIDRs  []string        `json:"cidrs,omitempty"`
+	CNAME  string          `json:"cname,omitempty"`
+	MinTTL metav1.Duration `json:"minTTL,omitempty"`
+}
+
+// HTTP configures the HTTP collector. Interval, Timeout and Method are used
+// for targets which do not set their own.
+type HTTP struct {
+	Interval metav1.Duration `json:"interval,omitempty"`
+	Timeout  metav1.Duration `json:"timeout,omitempty"`
+	Method   string          `json:"method,omitempty"`
+	Targets  []HTTPTarget    `json:"targets,omitempty"`
+}
+
+// HTTPTarget is a URL requested by the HTTP collector.
+type HTTPTarget struct {
+	URL      string            `json:"url"`
+	Method   string            `json:"method,omitempty"`
+	Interval metav1.Duration   `json:"interval,omitempty"`
+	Timeout  metav1.Duration   `json:"timeout,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+// Network configures the network collector. Interval and Timeout apply to
+// the net-exporter service and neighbours, and are used for targets which do
+// not set their own. ICMP also pings the neighbours, with ICMPCount echo
+// requests per round, which are also sent to targets with protocol icmp. UDP
+// sends UDPCount UDP echo requests per round to the neighbours, and with MTU
+// set, also probes the path MTU to them, expecting at least MTU. Topology,
+// Neighbours, MeshMaxSize and ZoneAware select the neighbours, see
+// network.Config. PodSelector is the label selector of the net-exporter pods.
+type Network struct {
+	Namespace   string          `json:"namespace,omitempty"`
+	Service     string          `json:"service,omitempty"`
+	Port        string          `json:"port,omitempty"`
+	Interval    metav1.Duration `json:"interval,omitempty"`
+	Timeout     metav1.Duration `json:"timeout,omitempty"`
+	Topology    string          `json:"topology,omitempty"`
+	Neighbours  int             `json:"neighbours,omitempty"`
+	MeshMaxSize int             `json:"meshMaxSize,omitempty"`
+	PodSelector string          `json:"podSelector,omitempty"`
+	ZoneAware   bool            `json:"zoneAware,omitempty"`
+	ICMP        bool            `json:"icmp,omitempty"`
+	ICMPCount   int             `json:"icmpCount,omitempty"`
+	UDP         bool            `json:"udp,omitempty"`
+	UDPCount    int             `json:"udpCount,omitempty"`
+	MTU         int             `json:"mtu,omitempty"`
+	Targets     []NetworkTarget `json:"targets,omitempty"`
+}
+
+// NetworkTarget is an additional host dialed by the network collector.
+type NetworkTarget struct {
+	Host       string            `json:"host"`
+	Interval   metav1.Duration   `json:"interval,omitempty"`
+	Timeout    metav1.Duration   `json:"timeout,omitempty"`
+	Protocol   string            `json:"protocol,omitempty"`
+	ServerName string            `json:"serverName,omitempty"`
+	CAFile     string            `json:"caFile,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+}
+
+// NTP configures the NTP collector. KernelState exposes the synchronization
+// state of the kernel clock of the node. Interval and Timeout are used for
+// targets which do not set their own.
+type NTP struct {
+	KernelState bool            `json:"kernelState,omitempty"`
+	Interval    metav1.Duration `json:"interval,omitempty"`
+	Timeout     metav1.Duration `json:"timeout,omitempty"`
+	Targets     []NTPTarget     `json:"targets,omitempty"`
+}
+
+// NTPTarget is a server the NTP collector syncs with.
+type NTPTarget struct {
+	Server   string            `json:"server"`
+	NTS      bool              `json:"nts,omitempty"`
+	Interval metav1.Duration   `json:"interval,omitempty"`
+	Timeout  metav1.Duration   `json:"timeout,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+// Read reads the configuration file at the given path, decoding it on top of
+// the given defaults, so that anything the file does not set is taken from
+// the defaults.
+func Read(path string, defaults Config) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, microerror.Mask(err)
+	}
+
+	return Parse(data, defaults)
+}
+
+// Parse decodes the given YAML or JSON configuration on top of the given
+// defaults.
+func Parse(data []byte, defaults Config) (Config, error) {
+	// Decode on top of a deep copy of the defaults, so that decoding into
+	// their slices does not modify them.
+	var c Config
+	{
+		b, err := json.Marshal(defaults)
+		if err != nil {
+			return Config{}, microerror.Mask(err)
+		}
+		err = json.Unmarshal(b, &c)
+		if err != nil {
+			return Config{}, microerror.Mask(err)
+		}
+	}
+
+	err := yaml.UnmarshalStrict(data, &c)
+	if err != nil {
+		return Config{}, microerror.Maskf(invalidConfigError, "failed to decode configuration: %s", err)
+	}
+
+	return c, nil
+}
+
+// DNSTargets returns the targets of the DNS collector, with defaults applied.
+func (c Config) DNSTargets() []dns.Target {
+	var targets []dns.Target
+
+	for _, t := range c.DNS.Targets {
+		target := dns.Target{
+			Host:       t.Host,
+			Interval:   orDefault(t.Interval, c.DNS.Interval),
+			Timeout:    orDefault(t.Timeout, c.DNS.Timeout),
+			Protocols:  t.Protocols,
+			QueryTypes: t.QueryTypes,
+			Expect: dns.Expectation{
+				IPs:    t.Expect.IPs,
+				CIDRs:  t.Expect.CIDRs,
+				CNAME:  t.Expect.CNAME,
+				MinTTL: t.Expect.MinTTL.Duration,
+			},
+			Labels: t.Labels,
+		}
+		if len(target.Protocols) == 0 {
+			target.Protocols = c.DNS.Protocols
+		}
+		if len(target.QueryTypes) == 0 {
+			target.QueryTypes = c.DNS.QueryTypes
+		}
+
+		targets = append(targets, target)
+	}
+
+	return targets
+}
+
+// DNSNodeLocalAddress returns the host:port of the node-local DNS cache, or an
+// empty string if it is disabled.
+func (c Config) DNSNodeLocalAddress() string {
+	if c.DNS.NodeLocal.Address == "" {
+		return ""
+	}
+
+	port := c.DNS.NodeLocal.Port
+	if port == 0 {
+		port = defaultDNSPort
+	}
+
+	return net.JoinHostPort(c.DNS.NodeLocal.Address, strconv.Itoa(port))
+}
+
+// HTTPTargets returns the targets of the HTTP collector, with defaults
+// applied.
+func (c Config) HTTPTargets() []http.Target {
+	var targets []http.Target
+
+	for _, t := range c.HTTP.Targets {
+		target := http.Target{
+			URL:      t.URL,
+			Method:   t.Method,
+			Interval: orDefault(t.Interval, c.HTTP.Interval),
+			Timeout:  orDefault(t.Timeout, c.HTTP.Timeout),
+			Labels:   t.Labels,
+		}
+		if target.Method == "" {
+			target.Method = c.HTTP.Method
+		}
+
+		targets = append(targets, target)
+	}
+
+	return targets
+}
+
+// NetworkTargets returns the additional targets of the network collector,
+// with defaults applied.
+func (c Config) NetworkTargets() []network.Target {
+	var targets []network.Target
+
+	for _, t := range c.Network.Targets {
+		target := network.Target{
+			Host:       t.Host,
+			Interval:   orDefault(t.Interval, c.Network.Interval),
+			Timeout:    orDefault(t.Timeout, c.Network.Timeout),
+			Protocol:   t.Protocol,
+			ServerName: t.ServerName,
+			CAFile:     t.CAFile,
+			Labels:     t.Labels,
+		}
+		if target.Protocol == "" {
+			target.Protocol = network.ProtocolTCP
+		}
+
+		targets = append(targets, target)
+	}
+
+	return targets
+}
+
+// NTPTargets returns the targets of the NTP collector, with defaults applied.
+func (c Config) NTPTargets() []ntp.Target {
+	var targets []ntp.Target
+
+	for _, t := range c.NTP.Targets {
+		targets = append(targets, ntp.Target{
+			Server:   t.Server,
+			NTS:      t.NTS,
+			Interval: orDefault(t.Interval, c.NTP.Interval),
+			Timeout:  orDefault(t.Timeout, c.NTP.Timeout),
+			Labels:   t.Labels,
+		})
+	}
+
+	return targets
+}
+
+func orDefault(d metav1.Duration, defaultDuration metav1.Duration) time.Duration {
+	if d.Duration == 0 {
+		return defaultDuration.Duration
+	}
+
+	return d.Duration
+}
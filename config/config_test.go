@@ -0,0 +1,270 @@
+package config
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/net-exporter/dns"
+	"github.com/giantswarm/net-exporter/http"
+	"github.com/giantswarm/net-exporter/ntp"
+)
+
+func Test_Parse(t *testing.T) {
+	defaults := Config{
+		DNS: DNS{
+			Namespace:  "kube-system",
+			Service:    "coredns",
+			Interval:   metav1.Duration{Duration: 30 * time.Second},
+			Timeout:    metav1.Duration{Duration: 5 * time.Second},
+			Protocols:  []string{dns.ProtocolUDP, dns.ProtocolTCP},
+			QueryTypes: []string{dns.QueryTypeA},
+			Targets:    []DNSTarget{{Host: "giantswarm.io."}},
+		},
+		HTTP: HTTP{
+			Interval: metav1.Duration{Duration: 30 * time.Second},
+			Timeout:  metav1.Duration{Duration: 5 * time.Second},
+			Method:   http.MethodGet,
+		},
+		NTP: NTP{
+			Interval: metav1.Duration{Duration: 30 * time.Second},
+			Timeout:  metav1.Duration{Duration: 5 * time.Second},
+			Targets:  []NTPTarget{{Server: "0.flatcar.pool.ntp.org"}},
+		},
+	}
+
+	testCases := []struct {
+		name                string
+		inputData           string
+		expectedDNSTargets  []dns.Target
+		expectedHTTPTargets []http.Target
+		expectedNTPTargets  []ntp.Target
+		errorMatcher        func(error) bool
+	}{
+		{
+			name:      "case 0: empty configuration keeps the defaults",
+			inputData: ``,
+			expectedDNSTargets: []dns.Target{
+				{
+					Host:       "giantswarm.io.",
+					Interval:   30 * time.Second,
+					Timeout:    5 * time.Second,
+					Protocols:  []string{dns.ProtocolUDP, dns.ProtocolTCP},
+					QueryTypes: []string{dns.QueryTypeA},
+				},
+			},
+			expectedNTPTargets: []ntp.Target{
+				{
+					Server:   "0.flatcar.pool.ntp.org",
+					Interval: 30 * time.Second,
+					Timeout:  5 * time.Second,
+				},
+			},
+		},
+		{
+			name: "case 1: targets override the defaults",
+			inputData: `
+dns:
+  interval: 1m
+  targets:
+  - host: kubernetes.default.svc.cluster.local.
+    protocols: [udp]
+    queryTypes: [A, AAAA]
+    timeout: 2s
+    labels:
+      scope: internal
+http:
+  targets:
+  - url: https://giantswarm.io/
+    method: HEAD
+    timeout: 2s
+  - url: http://kubernetes.default.svc.cluster.local/healthz
+ntp:
+  targets:
+  - server: time.example.com
+    interval: 10s
+  - server: nts.example.com
+    nts: true
+`,
+			expectedDNSTargets: []dns.Target{
+				{
+					Host:       "kubernetes.default.svc.cluster.local.",
+					Interval:   time.Minute,
+					Timeout:    2 * time.Second,
+					Protocols:  []string{dns.ProtocolUDP},
+					QueryTypes: []string{dns.QueryTypeA, dns.QueryTypeAAAA},
+					Labels:     map[string]string{"scope": "internal"},
+				},
+			},
+			expectedHTTPTargets: []http.Target{
+				{
+					URL:      "https://giantswarm.io/",
+					Method:   http.MethodHead,
+					Interval: 30 * time.Second,
+					Timeout:  2 * time.Second,
+				},
+				{
+					URL:      "http://kubernetes.default.svc.cluster.local/healthz",
+					Method:   http.MethodGet,
+					Interval: 30 * time.Second,
+					Timeout:  5 * time.Second,
+				},
+			},
+			expectedNTPTargets: []ntp.Target{
+				{
+					Server:   "time.example.com",
+					Interval: 10 * time.Second,
+					Timeout:  5 * time.Second,
+				},
+				{
+					Server:   "nts.example.com",
+					NTS:      true,
+					Interval: 30 * time.Second,
+					Timeout:  5 * time.Second,
+				},
+			},
+		},
+		{
+			name:      "case 2: JSON is accepted",
+			inputData: `{"ntp": {"targets": [{"server": "time.example.com"}]}}`,
+			expectedDNSTargets: []dns.Target{
+				{
+					Host:       "giantswarm.io.",
+					Interval:   30 * time.Second,
+					Timeout:    5 * time.Second,
+					Protocols:  []string{dns.ProtocolUDP, dns.ProtocolTCP},
+					QueryTypes: []string{dns.QueryTypeA},
+				},
+			},
+			expectedNTPTargets: []ntp.Target{
+				{
+					Server:   "time.example.com",
+					Interval: 30 * time.Second,
+					Timeout:  5 * time.Second,
+				},
+			},
+		},
+		{
+			name: "case 3: unknown fields are rejected",
+			inputData: `
+dns:
+  hosts: giantswarm.io.
+`,
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 4: invalid durations are rejected",
+			inputData: `
+ntp:
+  interval: often
+`,
+			errorMatcher: IsInvalidConfig,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			c, err := Parse([]byte(tc.inputData), defaults)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+
+			if !cmp.Equal(c.DNSTargets(), tc.expectedDNSTargets) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedDNSTargets, c.DNSTargets()))
+			}
+			if !cmp.Equal(c.HTTPTargets(), tc.expectedHTTPTargets) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedHTTPTargets, c.HTTPTargets()))
+			}
+			if !cmp.Equal(c.NTPTargets(), tc.expectedNTPTargets) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedNTPTargets, c.NTPTargets()))
+			}
+		})
+	}
+}
+
+func Test_Diff(t *testing.T) {
+	previous := Config{
+		DNS: DNS{
+			Targets: []DNSTarget{
+				{Host: "a."},
+				{Host: "b."},
+			},
+		},
+		NTP: NTP{
+			Targets: []NTPTarget{
+				{Server: "time.example.com"},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name         string
+		inputNext    Config
+		expectedDiff string
+	}{
+		{
+			name:         "case 0: same targets",
+			inputNext:    previous,
+			expectedDiff: "",
+		},
+		{
+			name: "case 1: added, removed and changed targets",
+			inputNext: Config{
+				DNS: DNS{
+					Targets: []DNSTarget{
+						{Host: "a.", Protocols: []string{dns.ProtocolUDP}},
+						{Host: "c."},
+					},
+				},
+				NTP: NTP{
+					Targets: []NTPTarget{
+						{Server: "time.example.com"},
+					},
+				},
+			},
+			expectedDiff: "dns: added [c.] removed [b.] changed [a.]",
+		},
+		{
+			name: "case 2: changes in multiple sections",
+			inputNext: Config{
+				DNS: DNS{
+					Targets: []DNSTarget{
+						{Host: "a."},
+						{Host: "b."},
+					},
+				},
+				Network: Network{
+					Targets: []NetworkTarget{
+						{Host: "10.0.0.1:443"},
+					},
+				},
+			},
+			expectedDiff: "network: added [10.0.0.1:443]; ntp: removed [time.example.com]",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			diff := Diff(previous, tc.inputNext)
+
+			if diff != tc.expectedDiff {
+				t.Fatalf("diff == %q, want %q", diff, tc.expectedDiff)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/net-exporter/dns"
+	"github.com/giantswarm/net-exporter/http"
+	"github.com/giantswarm/net-exporter/network"
+	"github.com/giantswarm/net-exporter/ntp"
+)
+
+// CheckReload returns an invalidConfigError if the given configurations differ
+// in settings which cannot be changed without restarting net-exporter.
+func CheckReload(previous, next Config) error {
+	fields := []struct {
+		name     string
+		previous any
+		next     any
+	}{
+		{name: "dns.namespace", previous: previous.DNS.Namespace, next: next.DNS.Namespace},
+		{name: "dns.service", previous: previous.DNS.Service, next: next.DNS.Service},
+		{name: "dns.perPod", previous: previous.DNS.PerPod, next: next.DNS.PerPod},
+		{name: "dns.nodeLocal", previous: previous.DNS.NodeLocal, next: next.DNS.NodeLocal},
+		{name: "network.namespace", previous: previous.Network.Namespace, next: next.Network.Namespace},
+		{name: "network.service", previous: previous.Network.Service, next: next.Network.Service},
+		{name: "network.port", previous: previous.Network.Port, next: next.Network.Port},
+		{name: "network.interval", previous: previous.Network.Interval, next: next.Network.Interval},
+		{name: "network.timeout", previous: previous.Network.Timeout, next: next.Network.Timeout},
+		{name: "network.topology", previous: previous.Network.Topology, next: next.Network.Topology},
+		{name: "network.neighbours", previous: previous.Network.Neighbours, next: next.Network.Neighbours},
+		{name: "network.meshMaxSize", previous: previous.Network.MeshMaxSize, next: next.Network.MeshMaxSize},
+		{name: "network.podSelector", previous: previous.Network.PodSelector, next: next.Network.PodSelector},
+		{name: "network.zoneAware", previous: previous.Network.ZoneAware, next: next.Network.ZoneAware},
+		{name: "network.icmp", previous: previous.Network.ICMP, next: next.Network.ICMP},
+		{name: "network.icmpCount", previous: previous.Network.ICMPCount, next: next.Network.ICMPCount},
+		{name: "network.udp", previous: previous.Network.UDP, next: next.Network.UDP},
+		{name: "network.udpCount", previous: previous.Network.UDPCount, next: next.Network.UDPCount},
+		{name: "network.mtu", previous: previous.Network.MTU, next: next.Network.MTU},
+		{name: "ntp.kernelState", previous: previous.NTP.KernelState, next: next.NTP.KernelState},
+	}
+
+	for _, f := range fields {
+		if f.previous != f.next {
+			return microerror.Maskf(invalidConfigError, "changing %s from %v to %v requires a restart", f.name, f.previous, f.next)
+		}
+	}
+
+	return nil
+}
+
+// Diff describes the targets added, removed and changed from the old to the
+// new configuration, e.g. "dns: added [a.] removed [b.]; ntp: changed [c]".
+// It returns an empty string if the targets are the same.
+func Diff(previous, next Config) string {
+	var sections []string
+
+	sections = appendDiff(sections, "dns", targetsByKey(previous.DNSTargets(), dnsKey), targetsByKey(next.DNSTargets(), dnsKey))
+	sections = appendDiff(sections, "http", targetsByKey(previous.HTTPTargets(), httpKey), targetsByKey(next.HTTPTargets(), httpKey))
+	sections = appendDiff(sections, "network", targetsByKey(previous.NetworkTargets(), networkKey), targetsByKey(next.NetworkTargets(), networkKey))
+	sections = appendDiff(sections, "ntp", targetsByKey(previous.NTPTargets(), ntpKey), targetsByKey(next.NTPTargets(), ntpKey))
+
+	return strings.Join(sections, "; ")
+}
+
+func appendDiff(sections []string, name string, previous, next map[string]any) []string {
+	var added, removed, changed []string
+
+	for key, t := range next {
+		o, ok := previous[key]
+		if !ok {
+			added = append(added, key)
+		} else if !reflect.DeepEqual(o, t) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range previous {
+		if _, ok := next[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+
+	var parts []string
+	for _, p := range []struct {
+		verb string
+		keys []string
+	}{
+		{verb: "added", keys: added},
+		{verb: "removed", keys: removed},
+		{verb: "changed", keys: changed},
+	} {
+		if len(p.keys) == 0 {
+			continue
+		}
+
+		sort.Strings(p.keys)
+		parts = append(parts, fmt.Sprintf("%s %v", p.verb, p.keys))
+	}
+
+	if len(parts) == 0 {
+		return sections
+	}
+
+	return append(sections, fmt.Sprintf("%s: %s", name, strings.Join(parts, " ")))
+}
+
+func targetsByKey[T any](targets []T, key func(T) string) map[string]any {
+	m := map[string]any{}
+	for _, t := range targets {
+		m[key(t)] = t
+	}
+
+	return m
+}
+
+func dnsKey(t dns.Target) string         { return t.Host }
+func httpKey(t http.Target) string       { return t.URL }
+func networkKey(t network.Target) string { return t.Host }
+func ntpKey(t ntp.Target) string         { return t.Server }
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "config"
+)
+
+// WatcherConfig provides the necessary configuration for creating a Watcher.
+type WatcherConfig struct {
+	Logger micrologger.Logger
+	// Reload applies a changed configuration to the running probes. It must
+	// either apply the configuration entirely or not at all.
+	Reload func(Config) error
+
+	// Current is the configuration the probes are running with.
+	Current Config
+	// Defaults are passed to Read when re-reading the file.
+	Defaults Config
+	// Interval is the time between two checks of the file for changes.
+	Interval time.Duration
+	Path     string
+}
+
+// Watcher watches a configuration file, which may be mounted from a
+// ConfigMap, and reloads the probes whenever its targets change.
+type Watcher struct {
+	logger micrologger.Logger
+	reload func(Config) error
+
+	current  Config
+	defaults Config
+	interval time.Duration
+	path     string
+
+	// data is the content of the file as of the last check.
+	data []byte
+
+	reloadCount          *prometheus.CounterVec
+	lastReloadSuccessful prometheus.Gauge
+}
+
+// NewWatcher creates a Watcher, given a WatcherConfig.
+func NewWatcher(config WatcherConfig) (*Watcher, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Reload == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Reload must not be empty", config)
+	}
+
+	if config.Interval <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Interval must be greater than zero", config)
+	}
+	if config.Path == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Path must not be empty", config)
+	}
+
+	reloadCount := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "", "reloads_total"),
+			Help: "Total number of configuration reloads, by result.",
+		},
+		[]string{"result"},
+	)
+	lastReloadSuccessful := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: prometheus.BuildFQName(namespace, "", "last_reload_successful"),
+		Help: "Whether the last configuration reload attempt was successful.",
+	})
+
+	prometheus.MustRegister(reloadCount)
+	prometheus.MustRegister(lastReloadSuccessful)
+
+	lastReloadSuccessful.Set(1)
+
+	w := &Watcher{
+		logger: config.Logger,
+		reload: config.Reload,
+
+		current:  config.Current,
+		defaults: config.Defaults,
+		interval: config.Interval,
+		path:     config.Path,
+
+		reloadCount:          reloadCount,
+		lastReloadSuccessful: lastReloadSuccessful,
+	}
+
+	return w, nil
+}
+
+// Run checks the file for changes once per interval, until the given context
+// is cancelled.
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+func (w *Watcher) check() {
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		w.logger.Log("level", "error", "message", "failed to read configuration file", "path", w.path, "stack", microerror.JSON(err))
+		return
+	}
+
+	// ConfigMap volumes are updated atomically, so the file is either
+	// entirely old or entirely new. Only attempt a reload once per change.
+	if bytes.Equal(data, w.data) {
+		return
+	}
+	w.data = data
+
+	next, err := Parse(data, w.defaults)
+	if err != nil {
+		w.failed(err)
+		return
+	}
+
+	if reflect.DeepEqual(next, w.current) {
+		return
+	}
+
+	err = CheckReload(w.current, next)
+	if err != nil {
+		w.failed(err)
+		return
+	}
+
+	err = w.reload(next)
+	if err != nil {
+		w.failed(err)
+		return
+	}
+
+	w.logger.Log("level", "info", "message", "reloaded configuration", "path", w.path, "diff", Diff(w.current, next))
+	w.reloadCount.WithLabelValues("success").Inc()
+	w.lastReloadSuccessful.Set(1)
+
+	w.current = next
+}
+
+func (w *Watcher) failed(err error) {
+	w.logger.Log("level", "error", "message", "failed to reload configuration", "path", w.path, "stack", microerror.JSON(err))
+	w.reloadCount.WithLabelValues("failure").Inc()
+	w.lastReloadSuccessful.Set(0)
+}
@@ -2,8 +2,12 @@ package dns
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/giantswarm/exporterkit/histogramvec"
@@ -11,8 +15,11 @@ import (
 	"github.com/giantswarm/micrologger"
 	dnsclient "github.com/miekg/dns"
 	"github.com/prometheus/client_golang/prometheus"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/informers"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	discoveryv1listers "k8s.io/client-go/listers/discovery/v1"
+
+	"github.com/giantswarm/net-exporter/scheduler"
 )
 
 const (
@@ -21,46 +28,88 @@ const (
 	bucketStart  = 0.001
 	bucketFactor = 2
 	numBuckets   = 15
+
+	// transportErrorRefused is the reason of transport errors caused by the
+	// DNS server refusing the connection, e.g. because it is down.
+	transportErrorRefused = "refused"
+	// transportErrorTimeout is the reason of transport errors caused by the
+	// DNS server not responding in time.
+	transportErrorTimeout = "timeout"
+	// transportErrorTruncated is the reason of transport errors caused by a
+	// truncated response, which did not fit into a UDP packet.
+	transportErrorTruncated = "truncated"
+	// transportErrorOther is the reason of all other transport errors.
+	transportErrorOther = "other"
 )
 
 // Config provides the necessary configuration for creating a Collector.
 type Config struct {
-	K8sClient kubernetes.Interface
-	Logger    micrologger.Logger
-	TCPClient *dnsclient.Client
-	UDPClient *dnsclient.Client
-
-	DisableTCPCheck bool
-	Hosts           []string
-	Service         string
-	Namespace       string
+	// EndpointSliceInformerFactory must be scoped to Namespace and to the
+	// EndpointSlices of Service, and is only used in per Pod mode.
+	// ServiceInformerFactory must be scoped to Namespace and Service. They
+	// are started by the caller once the Collector has been created.
+	EndpointSliceInformerFactory informers.SharedInformerFactory
+	ServiceInformerFactory       informers.SharedInformerFactory
+	Logger                       micrologger.Logger
+	TCPClient                    *dnsclient.Client
+	UDPClient                    *dnsclient.Client
+
+	Service   string
+	Namespace string
+	// PerPod queries each ready Pod of Service directly, as discovered by its
+	// EndpointSlices, instead of the Service itself. The metrics of each host
+	// are then labeled with the pod and node of the Pod.
+	PerPod bool
+	// NodeLocalAddress is the host:port of a node-local DNS cache, e.g.
+	// NodeLocal DNSCache. If set, each host is also resolved against it, and
+	// the metrics of each host are labeled with the resolver, either
+	// "cluster" or "nodelocal".
+	NodeLocalAddress string
+	Targets          []Target
 }
 
 // Collector implements the Collector interface, exposing DNS latency information.
 type Collector struct {
-	k8sClient kubernetes.Interface
-	logger    micrologger.Logger
-	tcpClient *dnsclient.Client
-	udpClient *dnsclient.Client
-
-	disableTCPCheck bool
-	hosts           []string
-	service         string
-	namespace       string
+	// endpointSliceLister is only set in per Pod mode.
+	endpointSliceLister discoveryv1listers.EndpointSliceLister
+	logger              micrologger.Logger
+	serviceLister       corev1listers.ServiceLister
+	tcpClient           *dnsclient.Client
+	udpClient           *dnsclient.Client
+
+	service          string
+	namespace        string
+	nodeLocalAddress string
+	perPod           bool
+	// serverLabelNames are the names of the labels identifying the DNS server
+	// of a series, which depend on the mode of the Collector.
+	serverLabelNames []string
+	// targets holds the configured Targets, keyed by host.
+	targets map[string]Target
+	// servers holds the DNS servers found by the last probe.
+	servers []server
+	mutex   sync.Mutex
 
 	tcpLatencyHistogramVec  *histogramvec.HistogramVec
 	tcpLatencyHistogramDesc *prometheus.Desc
 	udpLatencyHistogramVec  *histogramvec.HistogramVec
 	udpLatencyHistogramDesc *prometheus.Desc
 
-	errorCount        prometheus.Counter
-	resolveErrorCount *prometheus.CounterVec
+	answerMatch         *prometheus.GaugeVec
+	answerMismatchCount *prometheus.CounterVec
+	errorCount          prometheus.Counter
+	rcodeCount          *prometheus.CounterVec
+	resolveErrorCount   *prometheus.CounterVec
+	transportErrorCount *prometheus.CounterVec
 }
 
 // New creates a Collector, given a Config.
 func New(config Config) (*Collector, error) {
-	if config.K8sClient == nil {
-		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	if config.EndpointSliceInformerFactory == nil && config.PerPod {
+		return nil, microerror.Maskf(invalidConfigError, "%T.EndpointSliceInformerFactory must not be empty with %T.PerPod", config, config)
+	}
+	if config.ServiceInformerFactory == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ServiceInformerFactory must not be empty", config)
 	}
 	if config.Logger == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
@@ -72,9 +121,6 @@ func New(config Config) (*Collector, error) {
 		return nil, microerror.Maskf(invalidConfigError, "%T.UDPClient must not be empty", config)
 	}
 
-	if len(config.Hosts) == 0 {
-		return nil, microerror.Maskf(invalidConfigError, "%T.Host must not be empty", config)
-	}
 	if len(config.Service) == 0 {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Service must not be empty", config)
 	}
@@ -82,7 +128,32 @@ func New(config Config) (*Collector, error) {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Namespace must not be empty", config)
 	}
 
-	var err error
+	if config.NodeLocalAddress != "" {
+		_, _, err := net.SplitHostPort(config.NodeLocalAddress)
+		if err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "%T.NodeLocalAddress must be a host:port, got %#q", config, config.NodeLocalAddress)
+		}
+	}
+
+	err := ValidateTargets(config.Targets)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var serverLabelNames []string
+	if config.NodeLocalAddress != "" {
+		serverLabelNames = append(serverLabelNames, "resolver")
+	}
+	if config.PerPod {
+		serverLabelNames = append(serverLabelNames, "pod", "node")
+	}
+	serverLabelNames = append(serverLabelNames, "ip_family")
+
+	targets := map[string]Target{}
+	for _, t := range config.Targets {
+		targets[t.Host] = t
+	}
+
 	var tcpLatencyHistogramVec *histogramvec.HistogramVec
 	{
 		c := histogramvec.Config{
@@ -104,49 +175,83 @@ func New(config Config) (*Collector, error) {
 		}
 	}
 
+	answerMatch := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "", "answer_match"),
+			Help: "Whether the last answer matched the expected answer of the host.",
+		},
+		withServerLabels(serverLabelNames, "proto", "host", "qtype"),
+	)
+	answerMismatchCount := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "", "answer_mismatch_total"),
+			Help: "Total number of answers not matching the expected answer of the host.",
+		},
+		withServerLabels(serverLabelNames, "proto", "host", "qtype"),
+	)
 	errorCount := prometheus.NewCounter(prometheus.CounterOpts{
 		Name: prometheus.BuildFQName(namespace, "", "error_total"),
 		Help: "Total number of internal errors.",
 	})
+	rcodeCount := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "", "response_rcode_total"),
+			Help: "Total number of responses, by response code.",
+		},
+		withServerLabels(serverLabelNames, "proto", "host", "qtype", "rcode"),
+	)
 	resolveErrorCount := prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: prometheus.BuildFQName(namespace, "", "resolve_error_total"),
 			Help: "Total number of errors resolving hosts.",
 		},
-		[]string{"proto", "host"},
+		withServerLabels(serverLabelNames, "proto", "host", "qtype"),
+	)
+	transportErrorCount := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "", "transport_error_total"),
+			Help: "Total number of errors exchanging messages with the DNS server, by reason.",
+		},
+		withServerLabels(serverLabelNames, "proto", "host", "qtype", "reason"),
 	)
 
+	prometheus.MustRegister(answerMatch)
+	prometheus.MustRegister(answerMismatchCount)
 	prometheus.MustRegister(errorCount)
+	prometheus.MustRegister(rcodeCount)
 	prometheus.MustRegister(resolveErrorCount)
+	prometheus.MustRegister(transportErrorCount)
+
+	var endpointSliceLister discoveryv1listers.EndpointSliceLister
+	if config.PerPod {
+		endpointSliceLister = config.EndpointSliceInformerFactory.Discovery().V1().EndpointSlices().Lister()
+	}
 
 	collector := &Collector{
-		k8sClient: config.K8sClient,
-		logger:    config.Logger,
-		tcpClient: config.TCPClient,
-		udpClient: config.UDPClient,
-
-		disableTCPCheck: config.DisableTCPCheck,
-		hosts:           config.Hosts,
-		service:         config.Service,
-		namespace:       config.Namespace,
-
-		tcpLatencyHistogramVec: tcpLatencyHistogramVec,
-		tcpLatencyHistogramDesc: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "tcp_latency_seconds"),
-			"Histogram of latency of TCP DNS resolutions.",
-			[]string{"host"},
-			nil,
-		),
-		udpLatencyHistogramVec: udpLatencyHistogramVec,
-		udpLatencyHistogramDesc: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "udp_latency_seconds"),
-			"Histogram of latency of UDP DNS resolutions.",
-			[]string{"host"},
-			nil,
-		),
-
-		errorCount:        errorCount,
-		resolveErrorCount: resolveErrorCount,
+		endpointSliceLister: endpointSliceLister,
+		logger:              config.Logger,
+		serviceLister:       config.ServiceInformerFactory.Core().V1().Services().Lister(),
+		tcpClient:           config.TCPClient,
+		udpClient:           config.UDPClient,
+
+		service:          config.Service,
+		namespace:        config.Namespace,
+		nodeLocalAddress: config.NodeLocalAddress,
+		perPod:           config.PerPod,
+		serverLabelNames: serverLabelNames,
+		targets:          targets,
+
+		tcpLatencyHistogramVec:  tcpLatencyHistogramVec,
+		tcpLatencyHistogramDesc: newLatencyHistogramDesc(ProtocolTCP, serverLabelNames, nil),
+		udpLatencyHistogramVec:  udpLatencyHistogramVec,
+		udpLatencyHistogramDesc: newLatencyHistogramDesc(ProtocolUDP, serverLabelNames, nil),
+
+		answerMatch:         answerMatch,
+		answerMismatchCount: answerMismatchCount,
+		errorCount:          errorCount,
+		rcodeCount:          rcodeCount,
+		resolveErrorCount:   resolveErrorCount,
+		transportErrorCount: transportErrorCount,
 	}
 
 	return collector, nil
@@ -154,84 +259,365 @@ func New(config Config) (*Collector, error) {
 
 // Describe implements the Describe method of the Collector interface.
 func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
-	if !c.disableTCPCheck {
-		ch <- c.tcpLatencyHistogramDesc
-	}
+	ch <- c.tcpLatencyHistogramDesc
 	ch <- c.udpLatencyHistogramDesc
 }
 
-func (c *Collector) resolve(proto string, client *dnsclient.Client, host string, dnsServer string, latencyHistogramVec *histogramvec.HistogramVec) {
+func (c *Collector) resolve(ctx context.Context, proto string, client *dnsclient.Client, t Target, qtype string, s server, latencyHistogramVec *histogramvec.HistogramVec) {
+	host := t.Host
+	labels := c.labels(proto, host, qtype, s)
 	start := time.Now()
 
-	message := &dnsclient.Msg{}
-	message.SetQuestion(host, dnsclient.TypeA)
+	msg, err := exchange(ctx, client, host, queryTypes[qtype], t.Timeout, s.address(proto))
+	if err != nil {
+		c.logger.Log("level", "error", "message", fmt.Sprintf("could not resolve dns for host %#q, query type %#q and protocol %#q", host, qtype, proto), "server", s.address(proto), "stack", microerror.JSON(err))
+		c.transportErrorCount.With(withLabel(labels, "reason", transportErrorReason(err))).Inc()
+		c.resolveErrorCount.With(labels).Inc()
+		return
+	}
+
+	rcode := dnsclient.RcodeToString[msg.Rcode]
+	c.rcodeCount.With(withLabel(labels, "rcode", rcode)).Inc()
 
-	msg, _, err := client.Exchange(message, fmt.Sprintf("%s:53", dnsServer))
-	if err != nil || len(msg.Answer) == 0 {
-		c.logger.Log("level", "error", "message", fmt.Sprintf("could not resolve dns for host %#q and protocol %#q", host, proto), "stack", microerror.JSON(err))
-		c.resolveErrorCount.WithLabelValues(proto, host).Inc()
+	if msg.Truncated {
+		c.logger.Log("level", "error", "message", fmt.Sprintf("truncated dns response for host %#q, query type %#q and protocol %#q", host, qtype, proto), "server", s.address(proto))
+		c.transportErrorCount.With(withLabel(labels, "reason", transportErrorTruncated)).Inc()
+		c.resolveErrorCount.With(labels).Inc()
+		return
+	}
+	if msg.Rcode != dnsclient.RcodeSuccess || len(msg.Answer) == 0 {
+		c.logger.Log("level", "error", "message", fmt.Sprintf("could not resolve dns for host %#q, query type %#q and protocol %#q", host, qtype, proto), "server", s.address(proto), "rcode", rcode)
+		c.resolveErrorCount.With(labels).Inc()
 		return
 	}
 
 	elapsed := time.Since(start)
 
-	err = latencyHistogramVec.Add(host, elapsed.Seconds())
+	if !t.Expect.isEmpty() {
+		c.checkAnswer(t, msg.Answer, labels)
+	}
+
+	err = latencyHistogramVec.Add(latencyKey(host, qtype, s), elapsed.Seconds())
 	if err != nil {
-		c.logger.Log("level", "error", "message", fmt.Sprintf("failed to update latency histogram for host %#q and protocol %#q", host, proto), "stack", microerror.JSON(err))
-		c.resolveErrorCount.WithLabelValues(proto, host).Inc()
+		c.logger.Log("level", "error", "message", fmt.Sprintf("failed to update latency histogram for host %#q, query type %#q and protocol %#q", host, qtype, proto), "stack", microerror.JSON(err))
+		c.resolveErrorCount.With(labels).Inc()
 		return
 	}
 }
 
-// Collect implements the Collect method of the Collector interface.
-func (c *Collector) Collect(ch chan<- prometheus.Metric) {
-	ctx := context.Background()
-	service, err := c.k8sClient.CoreV1().Services(c.namespace).Get(ctx, c.service, metav1.GetOptions{})
+// checkAnswer matches the given answer against the expected answer of the
+// given Target, and records the result in the series of the given labels.
+func (c *Collector) checkAnswer(t Target, answer []dnsclient.RR, labels prometheus.Labels) {
+	err := t.Expect.match(answer)
 	if err != nil {
-		c.logger.Log("level", "error", "message", "could not collect service from kubernetes api", "stack", microerror.JSON(err))
-		c.errorCount.Inc()
+		c.logger.Log("level", "warning", "message", fmt.Sprintf("unexpected dns answer for host %#q", t.Host), "labels", fmt.Sprintf("%v", labels), "stack", microerror.JSON(err))
+		c.answerMismatchCount.With(labels).Inc()
+		c.answerMatch.With(labels).Set(0)
 		return
 	}
 
-	var wg sync.WaitGroup
+	c.answerMatch.With(labels).Set(1)
+}
 
-	for _, host := range c.hosts {
-		if !c.disableTCPCheck {
-			wg.Add(1)
-			go func(host string) {
-				defer wg.Done()
+// Resolve queries the records of the given type of the given host once over
+// the given protocol against the DNS server at the given host:port address,
+// without recording any metrics. It serves on-demand probes.
+func (c *Collector) Resolve(ctx context.Context, proto string, address string, host string, qtype string, timeout time.Duration) (*dnsclient.Msg, error) {
+	t, ok := queryTypes[qtype]
+	if !ok {
+		return nil, microerror.Maskf(invalidConfigError, "unsupported query type %#q", qtype)
+	}
 
-				c.resolve("tcp", c.tcpClient, host, service.Spec.ClusterIP, c.tcpLatencyHistogramVec)
-			}(host)
-		}
+	client := c.udpClient
+	if proto == ProtocolTCP {
+		client = c.tcpClient
+	}
 
-		wg.Add(1)
-		go func(host string) {
-			defer wg.Done()
+	msg, err := exchange(ctx, client, host, t, timeout, address)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
 
-			c.resolve("udp", c.udpClient, host, service.Spec.ClusterIP, c.udpLatencyHistogramVec)
-		}(host)
+	return msg, nil
+}
+
+// Jobs implements the Prober interface of the scheduler package.
+func (c *Collector) Jobs() []scheduler.Job {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var jobs []scheduler.Job
+
+	for host, t := range c.targets {
+		jobs = append(jobs, scheduler.Job{
+			Name:     fmt.Sprintf("%s/%s", namespace, host),
+			Interval: t.Interval,
+			Run: func(ctx context.Context) {
+				c.probe(ctx, host)
+			},
+		})
 	}
 
-	wg.Wait()
+	return jobs
+}
+
+// Reconfigure replaces the Targets of the Collector, and removes the metrics
+// of hosts, protocols and query types which are not probed anymore. The jobs
+// of the Collector change accordingly, and must be synced with the scheduler.
+func (c *Collector) Reconfigure(targets []Target) error {
+	err := ValidateTargets(targets)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
-	c.tcpLatencyHistogramVec.Ensure(c.hosts)
-	c.udpLatencyHistogramVec.Ensure(c.hosts)
+	newTargets := map[string]Target{}
+	for _, t := range targets {
+		newTargets[t.Host] = t
+	}
 
-	if !c.disableTCPCheck {
-		for host, histogram := range c.tcpLatencyHistogramVec.Histograms() {
-			ch <- prometheus.MustNewConstHistogram(
-				c.tcpLatencyHistogramDesc,
-				histogram.Count(), histogram.Sum(), histogram.Buckets(),
-				host,
-			)
+	for host, t := range c.targets {
+		for _, proto := range t.Protocols {
+			for _, qtype := range t.QueryTypes {
+				removed := !newTargets[host].hasProtocol(proto) || !newTargets[host].hasQueryType(qtype)
+				if removed {
+					labels := prometheus.Labels{"proto": proto, "host": host, "qtype": qtype}
+					c.rcodeCount.DeletePartialMatch(labels)
+					c.resolveErrorCount.DeletePartialMatch(labels)
+					c.transportErrorCount.DeletePartialMatch(labels)
+				}
+				if removed || newTargets[host].Expect.isEmpty() {
+					labels := prometheus.Labels{"proto": proto, "host": host, "qtype": qtype}
+					c.answerMatch.DeletePartialMatch(labels)
+					c.answerMismatchCount.DeletePartialMatch(labels)
+				}
+			}
 		}
 	}
-	for host, histogram := range c.udpLatencyHistogramVec.Histograms() {
+
+	c.targets = newTargets
+	c.ensureHistograms()
+
+	return nil
+}
+
+// updateServers replaces the DNS servers of the Collector, and removes the
+// metrics of Pods which are not queried anymore.
+func (c *Collector) updateServers(servers []server) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.perPod {
+		pods := map[string]bool{}
+		for _, s := range servers {
+			pods[s.pod] = true
+		}
+
+		for _, s := range c.servers {
+			if pods[s.pod] {
+				continue
+			}
+
+			labels := prometheus.Labels{"pod": s.pod}
+			c.answerMatch.DeletePartialMatch(labels)
+			c.answerMismatchCount.DeletePartialMatch(labels)
+			c.rcodeCount.DeletePartialMatch(labels)
+			c.resolveErrorCount.DeletePartialMatch(labels)
+			c.transportErrorCount.DeletePartialMatch(labels)
+		}
+	}
+
+	c.servers = servers
+	c.ensureHistograms()
+}
+
+// ensureHistograms removes the latency histograms of all combinations of
+// host, query type and DNS server which are not probed anymore. It must be
+// called with the mutex held.
+func (c *Collector) ensureHistograms() {
+	var tcpKeys []string
+	var udpKeys []string
+	for host, t := range c.targets {
+		for _, qtype := range t.QueryTypes {
+			for _, s := range c.servers {
+				if t.hasProtocol(ProtocolTCP) {
+					tcpKeys = append(tcpKeys, latencyKey(host, qtype, s))
+				}
+				if t.hasProtocol(ProtocolUDP) {
+					udpKeys = append(udpKeys, latencyKey(host, qtype, s))
+				}
+			}
+		}
+	}
+
+	c.tcpLatencyHistogramVec.Ensure(tcpKeys)
+	c.udpLatencyHistogramVec.Ensure(udpKeys)
+}
+
+// Collect implements the Collect method of the Collector interface.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.collectHistograms(ch, ProtocolTCP, c.tcpLatencyHistogramVec)
+	c.collectHistograms(ch, ProtocolUDP, c.udpLatencyHistogramVec)
+}
+
+// collectHistograms sends the latency histograms of the given protocol to the
+// given channel. It must be called with the mutex held.
+func (c *Collector) collectHistograms(ch chan<- prometheus.Metric, proto string, latencyHistogramVec *histogramvec.HistogramVec) {
+	for key, histogram := range latencyHistogramVec.Histograms() {
+		host, qtype, s := splitLatencyKey(key)
+		t, ok := c.targets[host]
+		if !ok || !t.hasQueryType(qtype) {
+			continue
+		}
+
+		labelValues := []string{host, qtype}
+		for _, name := range c.serverLabelNames {
+			labelValues = append(labelValues, s.labels()[name])
+		}
+
 		ch <- prometheus.MustNewConstHistogram(
-			c.udpLatencyHistogramDesc,
+			newLatencyHistogramDesc(proto, c.serverLabelNames, t.Labels),
 			histogram.Count(), histogram.Sum(), histogram.Buckets(),
-			host,
+			labelValues...,
 		)
 	}
 }
+
+func (c *Collector) probe(ctx context.Context, host string) {
+	c.mutex.Lock()
+	t, ok := c.targets[host]
+	c.mutex.Unlock()
+	if !ok {
+		return
+	}
+
+	servers, err := c.listServers()
+	if err != nil {
+		c.logger.Log("level", "error", "message", "could not collect dns servers from informer cache", "stack", microerror.JSON(err))
+		c.errorCount.Inc()
+		return
+	}
+
+	c.updateServers(servers)
+
+	var wg sync.WaitGroup
+
+	for _, qtype := range t.QueryTypes {
+		for _, s := range servers {
+			if t.hasProtocol(ProtocolTCP) {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					c.resolve(ctx, ProtocolTCP, c.tcpClient, t, qtype, s, c.tcpLatencyHistogramVec)
+				}()
+			}
+
+			if t.hasProtocol(ProtocolUDP) {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					c.resolve(ctx, ProtocolUDP, c.udpClient, t, qtype, s, c.udpLatencyHistogramVec)
+				}()
+			}
+		}
+	}
+
+	wg.Wait()
+}
+
+// exchange sends a query for the records of the given type of the given host
+// to the DNS server at the given address, and returns the response.
+func exchange(ctx context.Context, client *dnsclient.Client, host string, qtype uint16, timeout time.Duration, address string) (*dnsclient.Msg, error) {
+	message := &dnsclient.Msg{}
+	message.SetQuestion(host, qtype)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	msg, _, err := client.ExchangeContext(ctx, message, address)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return msg, nil
+}
+
+// transportErrorReason classifies the given error of an exchange with the DNS
+// server.
+func transportErrorReason(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return transportErrorTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return transportErrorTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return transportErrorRefused
+	default:
+		return transportErrorOther
+	}
+}
+
+// labels returns the labels of the series of the given protocol, host and
+// query type, queried from the given DNS server.
+func (c *Collector) labels(proto string, host string, qtype string, s server) prometheus.Labels {
+	labels := prometheus.Labels{"proto": proto, "host": host, "qtype": qtype}
+	for _, name := range c.serverLabelNames {
+		labels[name] = s.labels()[name]
+	}
+
+	return labels
+}
+
+// withLabel returns a copy of the given labels with the given label added.
+func withLabel(labels prometheus.Labels, name string, value string) prometheus.Labels {
+	l := prometheus.Labels{name: value}
+	for k, v := range labels {
+		l[k] = v
+	}
+
+	return l
+}
+
+// withServerLabels appends the given names of the labels identifying the DNS
+// server to the given label names.
+func withServerLabels(serverLabelNames []string, labelNames ...string) []string {
+	return append(labelNames, serverLabelNames...)
+}
+
+// latencyKey returns the key of the latency histogram of the given host and
+// query type, queried from the given DNS server. Neither query types,
+// resolvers, Pod and Node names nor IP families contain a slash, so the key
+// can be split unambiguously by splitLatencyKey.
+func latencyKey(host string, qtype string, s server) string {
+	return strings.Join([]string{qtype, s.resolver, s.pod, s.node, s.ipFamily, host}, "/")
+}
+
+// splitLatencyKey returns the host, query type and DNS server of the given
+// latency histogram key. The addresses of the DNS server are not set.
+func splitLatencyKey(key string) (string, string, server) {
+	parts := strings.SplitN(key, "/", 6)
+	if len(parts) != 6 {
+		return key, "", server{}
+	}
+
+	return parts[5], parts[0], server{resolver: parts[1], pod: parts[2], node: parts[3], ipFamily: parts[4]}
+}
+
+// newLatencyHistogramDesc returns the descriptor of the latency histograms of
+// the given protocol, with the given server labels and constant labels.
+func newLatencyHistogramDesc(proto string, serverLabelNames []string, constLabels map[string]string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", fmt.Sprintf("%s_latency_seconds", proto)),
+		fmt.Sprintf("Histogram of latency of %s DNS resolutions.", strings.ToUpper(proto)),
+		withServerLabels(serverLabelNames, "host", "qtype"),
+		constLabels,
+	)
+}
@@ -0,0 +1,58 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/giantswarm/microerror"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func Test_transportErrorReason(t *testing.T) {
+	testCases := []struct {
+		name           string
+		inputError     error
+		expectedReason string
+	}{
+		{
+			name:           "case 0: exceeded context deadline is a timeout",
+			inputError:     microerror.Mask(context.DeadlineExceeded),
+			expectedReason: transportErrorTimeout,
+		},
+		{
+			name:           "case 1: network timeout is a timeout",
+			inputError:     microerror.Mask(&net.OpError{Op: "read", Net: "udp", Err: timeoutError{}}),
+			expectedReason: transportErrorTimeout,
+		},
+		{
+			name:           "case 2: refused connection is refused",
+			inputError:     microerror.Mask(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}),
+			expectedReason: transportErrorRefused,
+		},
+		{
+			name:           "case 3: anything else is other",
+			inputError:     microerror.Mask(errors.New("dns: bad rdata")),
+			expectedReason: transportErrorOther,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			reason := transportErrorReason(tc.inputError)
+
+			if reason != tc.expectedReason {
+				t.Fatalf("reason == %q, want %q", reason, tc.expectedReason)
+			}
+		})
+	}
+}
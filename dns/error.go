@@ -12,3 +12,12 @@ var invalidConfigError = &microerror.Error{
 func IsInvalidConfig(err error) bool {
 	return microerror.Cause(err) == invalidConfigError
 }
+
+var answerMismatchError = &microerror.Error{
+	Kind: "answerMismatchError",
+}
+
+// IsAnswerMismatch asserts answerMismatchError.
+func IsAnswerMismatch(err error) bool {
+	return microerror.Cause(err) == answerMismatchError
+}
@@ -0,0 +1,122 @@
+package dns
+
+import (
+	"net"
+	"regexp"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	dnsclient "github.com/miekg/dns"
+)
+
+// Expectation describes the answers a Target is expected to resolve to. All
+// fields are optional, and an empty Expectation matches any answer.
+type Expectation struct {
+	// IPs are the addresses A and AAAA records may resolve to. An address
+	// matches if it is in IPs or in one of CIDRs.
+	IPs []string
+	// CIDRs are the ranges A and AAAA records may resolve to.
+	CIDRs []string
+	// CNAME is a regular expression all CNAME records must match. If set, the
+	// answer must contain at least one CNAME record.
+	CNAME string
+	// MinTTL is the minimum TTL of all records in the answer.
+	MinTTL time.Duration
+}
+
+// isEmpty returns true if the Expectation matches any answer.
+func (e Expectation) isEmpty() bool {
+	return len(e.IPs) == 0 && len(e.CIDRs) == 0 && e.CNAME == "" && e.MinTTL == 0
+}
+
+// validate returns an invalidConfigError describing the first invalid field,
+// if any. Field names are prefixed with the given path of the Expectation.
+func (e Expectation) validate(path string) error {
+	for _, ip := range e.IPs {
+		if net.ParseIP(ip) == nil {
+			return microerror.Maskf(invalidConfigError, "%s.IPs contains invalid IP %#q", path, ip)
+		}
+	}
+	for _, cidr := range e.CIDRs {
+		_, _, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return microerror.Maskf(invalidConfigError, "%s.CIDRs contains invalid CIDR %#q", path, cidr)
+		}
+	}
+	if e.CNAME != "" {
+		_, err := regexp.Compile(e.CNAME)
+		if err != nil {
+			return microerror.Maskf(invalidConfigError, "%s.CNAME is not a valid regular expression: %s", path, err)
+		}
+	}
+	if e.MinTTL < 0 {
+		return microerror.Maskf(invalidConfigError, "%s.MinTTL must not be negative", path)
+	}
+
+	return nil
+}
+
+// match returns an answerMismatchError describing the first record of the
+// given answer which does not meet the Expectation, if any. The Expectation
+// must be valid.
+func (e Expectation) match(answer []dnsclient.RR) error {
+	var networks []*net.IPNet
+	for _, ip := range e.IPs {
+		ip := net.ParseIP(ip)
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = 8 * net.IPv4len
+		}
+		networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	for _, cidr := range e.CIDRs {
+		_, network, _ := net.ParseCIDR(cidr)
+		networks = append(networks, network)
+	}
+
+	var cname *regexp.Regexp
+	if e.CNAME != "" {
+		cname = regexp.MustCompile(e.CNAME)
+	}
+
+	var cnames int
+	for _, rr := range answer {
+		if e.MinTTL > 0 && time.Duration(rr.Header().Ttl)*time.Second < e.MinTTL {
+			return microerror.Maskf(answerMismatchError, "TTL %ds of record %#q is below %s", rr.Header().Ttl, rr.String(), e.MinTTL)
+		}
+
+		var ip net.IP
+		switch r := rr.(type) {
+		case *dnsclient.A:
+			ip = r.A
+		case *dnsclient.AAAA:
+			ip = r.AAAA
+		case *dnsclient.CNAME:
+			cnames++
+			if cname != nil && !cname.MatchString(r.Target) {
+				return microerror.Maskf(answerMismatchError, "CNAME %#q does not match %#q", r.Target, e.CNAME)
+			}
+		}
+
+		if ip != nil && len(networks) > 0 && !containsIP(networks, ip) {
+			return microerror.Maskf(answerMismatchError, "IP %s is not expected", ip)
+		}
+	}
+
+	if cname != nil && cnames == 0 {
+		return microerror.Maskf(answerMismatchError, "answer contains no CNAME record")
+	}
+
+	return nil
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, n := range networks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
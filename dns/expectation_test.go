@@ -0,0 +1,106 @@
+package dns
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	dnsclient "github.com/miekg/dns"
+)
+
+func Test_Expectation_match(t *testing.T) {
+	testCases := []struct {
+		name         string
+		inputExpect  Expectation
+		inputAnswer  []string
+		errorMatcher func(error) bool
+	}{
+		{
+			name:        "case 0: empty expectation matches any answer",
+			inputExpect: Expectation{},
+			inputAnswer: []string{"giantswarm.io. 60 IN A 192.0.2.1"},
+		},
+		{
+			name:        "case 1: expected IP matches",
+			inputExpect: Expectation{IPs: []string{"192.0.2.1"}},
+			inputAnswer: []string{"giantswarm.io. 60 IN A 192.0.2.1"},
+		},
+		{
+			name:         "case 2: unexpected IP does not match",
+			inputExpect:  Expectation{IPs: []string{"192.0.2.1"}},
+			inputAnswer:  []string{"giantswarm.io. 60 IN A 192.0.2.2"},
+			errorMatcher: IsAnswerMismatch,
+		},
+		{
+			name:        "case 3: IPs in CIDRs match",
+			inputExpect: Expectation{CIDRs: []string{"192.0.2.0/24", "2001:db8::/32"}},
+			inputAnswer: []string{
+				"giantswarm.io. 60 IN A 192.0.2.2",
+				"giantswarm.io. 60 IN AAAA 2001:db8::1",
+			},
+		},
+		{
+			name:         "case 4: IPs outside CIDRs do not match",
+			inputExpect:  Expectation{CIDRs: []string{"192.0.2.0/24"}},
+			inputAnswer:  []string{"giantswarm.io. 60 IN A 198.51.100.1"},
+			errorMatcher: IsAnswerMismatch,
+		},
+		{
+			name:        "case 5: CNAME matching regular expression matches",
+			inputExpect: Expectation{CNAME: `\.example\.com\.$`},
+			inputAnswer: []string{
+				"www.giantswarm.io. 60 IN CNAME lb.example.com.",
+				"lb.example.com. 60 IN A 192.0.2.1",
+			},
+		},
+		{
+			name:         "case 6: CNAME not matching regular expression does not match",
+			inputExpect:  Expectation{CNAME: `\.example\.com\.$`},
+			inputAnswer:  []string{"www.giantswarm.io. 60 IN CNAME lb.example.org."},
+			errorMatcher: IsAnswerMismatch,
+		},
+		{
+			name:         "case 7: missing CNAME does not match",
+			inputExpect:  Expectation{CNAME: `\.example\.com\.$`},
+			inputAnswer:  []string{"www.giantswarm.io. 60 IN A 192.0.2.1"},
+			errorMatcher: IsAnswerMismatch,
+		},
+		{
+			name:         "case 8: TTL below minimum does not match",
+			inputExpect:  Expectation{MinTTL: time.Minute},
+			inputAnswer:  []string{"giantswarm.io. 30 IN A 192.0.2.1"},
+			errorMatcher: IsAnswerMismatch,
+		},
+		{
+			name:        "case 9: TTL at minimum matches",
+			inputExpect: Expectation{MinTTL: time.Minute},
+			inputAnswer: []string{"giantswarm.io. 60 IN A 192.0.2.1"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var answer []dnsclient.RR
+			for _, s := range tc.inputAnswer {
+				rr, err := dnsclient.NewRR(s)
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+				answer = append(answer, rr)
+			}
+
+			err := tc.inputExpect.match(answer)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package dns
+
+import (
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// dnsPort is the port of the DNS service, and the port of its Pods if
+	// their EndpointSlices do not declare one.
+	dnsPort = 53
+
+	// resolverCluster is the resolver of the DNS service and its Pods.
+	resolverCluster = "cluster"
+	// resolverNodeLocal is the resolver of the node-local DNS cache.
+	resolverNodeLocal = "nodelocal"
+
+	ipFamilyIPv4 = "ipv4"
+	ipFamilyIPv6 = "ipv6"
+)
+
+// server is a DNS server queries are sent to. It is either the DNS service,
+// one of its Pods, or the node-local DNS cache, over one IP family. pod and
+// node are only set for Pods of the DNS service.
+type server struct {
+	resolver string
+	pod      string
+	node     string
+	ipFamily string
+
+	tcpAddress string
+	udpAddress string
+}
+
+// address returns the address queries of the given protocol are sent to.
+func (s server) address(proto string) string {
+	if proto == ProtocolTCP {
+		return s.tcpAddress
+	}
+
+	return s.udpAddress
+}
+
+// labels returns the values of all labels identifying the server.
+func (s server) labels() map[string]string {
+	return map[string]string{
+		"resolver":  s.resolver,
+		"pod":       s.pod,
+		"node":      s.node,
+		"ip_family": s.ipFamily,
+	}
+}
+
+// listServers returns the DNS servers to query. These are the ready Pods of
+// the DNS service in per Pod mode, or else the DNS service itself, followed
+// by the node-local DNS cache, if any.
+func (c *Collector) listServers() ([]server, error) {
+	servers, err := c.listClusterServers()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if c.nodeLocalAddress != "" {
+		servers = append(servers, server{
+			resolver:   resolverNodeLocal,
+			ipFamily:   ipFamily(c.nodeLocalAddress),
+			tcpAddress: c.nodeLocalAddress,
+			udpAddress: c.nodeLocalAddress,
+		})
+	}
+
+	return servers, nil
+}
+
+func (c *Collector) listClusterServers() ([]server, error) {
+	if !c.perPod {
+		service, err := c.serviceLister.Services(c.namespace).Get(c.service)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		clusterIPs := service.Spec.ClusterIPs
+		if len(clusterIPs) == 0 {
+			clusterIPs = []string{service.Spec.ClusterIP}
+		}
+
+		// Dual-stack services hold one cluster IP per IP family.
+		var servers []server
+		for _, clusterIP := range clusterIPs {
+			address := net.JoinHostPort(clusterIP, strconv.Itoa(dnsPort))
+			servers = append(servers, server{
+				resolver:   resolverCluster,
+				ipFamily:   ipFamily(address),
+				tcpAddress: address,
+				udpAddress: address,
+			})
+		}
+
+		return servers, nil
+	}
+
+	selector := labels.SelectorFromSet(labels.Set{discoveryv1.LabelServiceName: c.service})
+	endpointSlices, err := c.endpointSliceLister.EndpointSlices(c.namespace).List(selector)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	seen := map[string]bool{}
+	var servers []server
+	for _, endpointSlice := range endpointSlices {
+		tcpPort := dnsPort
+		udpPort := dnsPort
+		for _, p := range endpointSlice.Ports {
+			if p.Port == nil || p.Protocol == nil {
+				continue
+			}
+			switch *p.Protocol {
+			case corev1.ProtocolTCP:
+				tcpPort = int(*p.Port)
+			case corev1.ProtocolUDP:
+				udpPort = int(*p.Port)
+			}
+		}
+
+		for _, endpoint := range endpointSlice.Endpoints {
+			if len(endpoint.Addresses) == 0 {
+				continue
+			}
+			// Only query the Pods the DNS service routes to.
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue
+			}
+
+			address := endpoint.Addresses[0]
+
+			s := server{
+				resolver:   resolverCluster,
+				pod:        address,
+				ipFamily:   ipFamily(address),
+				tcpAddress: net.JoinHostPort(address, strconv.Itoa(tcpPort)),
+				udpAddress: net.JoinHostPort(address, strconv.Itoa(udpPort)),
+			}
+			if endpoint.TargetRef != nil && endpoint.TargetRef.Kind == "Pod" {
+				s.pod = endpoint.TargetRef.Name
+			}
+			if endpoint.NodeName != nil {
+				s.node = *endpoint.NodeName
+			}
+
+			// A Pod may be listed by several EndpointSlices, e.g. during
+			// their updates. Dual-stack Pods are queried once per IP
+			// family, which have their own EndpointSlices.
+			if seen[s.pod+"/"+s.ipFamily] {
+				continue
+			}
+			seen[s.pod+"/"+s.ipFamily] = true
+
+			servers = append(servers, s)
+		}
+	}
+
+	sort.Slice(servers, func(i, j int) bool {
+		if servers[i].pod != servers[j].pod {
+			return servers[i].pod < servers[j].pod
+		}
+		return servers[i].ipFamily < servers[j].ipFamily
+	})
+
+	return servers, nil
+}
+
+// ipFamily returns the IP family of the given host:port or IP. It is empty for
+// hosts which are not IPs.
+func ipFamily(address string) string {
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		address = host
+	}
+
+	ip := net.ParseIP(address)
+	switch {
+	case ip == nil:
+		return ""
+	case ip.To4() != nil:
+		return ipFamilyIPv4
+	default:
+		return ipFamilyIPv6
+	}
+}
@@ -0,0 +1,128 @@
+package dns
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_listServers(t *testing.T) {
+	ready := true
+	notReady := false
+	nodeA := "node-a"
+	nodeB := "node-b"
+	tcp := corev1.ProtocolTCP
+	udp := corev1.ProtocolUDP
+	dnsTCPPort := int32(1053)
+	dnsUDPPort := int32(1053)
+
+	endpointSlice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "coredns-abcde",
+			Namespace: "kube-system",
+			Labels: map[string]string{
+				discoveryv1.LabelServiceName: "coredns",
+			},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Endpoints: []discoveryv1.Endpoint{
+			{
+				Addresses:  []string{"10.0.0.2"},
+				Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+				NodeName:   &nodeB,
+				TargetRef:  &corev1.ObjectReference{Kind: "Pod", Name: "coredns-2"},
+			},
+			{
+				Addresses:  []string{"10.0.0.1"},
+				Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+				NodeName:   &nodeA,
+				TargetRef:  &corev1.ObjectReference{Kind: "Pod", Name: "coredns-1"},
+			},
+			{
+				Addresses:  []string{"10.0.0.3"},
+				Conditions: discoveryv1.EndpointConditions{Ready: &notReady},
+				NodeName:   &nodeA,
+				TargetRef:  &corev1.ObjectReference{Kind: "Pod", Name: "coredns-3"},
+			},
+		},
+		Ports: []discoveryv1.EndpointPort{
+			{Protocol: &tcp, Port: &dnsTCPPort},
+			{Protocol: &udp, Port: &dnsUDPPort},
+		},
+	}
+	ipv6EndpointSlice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "coredns-fghij",
+			Namespace: "kube-system",
+			Labels: map[string]string{
+				discoveryv1.LabelServiceName: "coredns",
+			},
+		},
+		AddressType: discoveryv1.AddressTypeIPv6,
+		Endpoints: []discoveryv1.Endpoint{
+			{
+				Addresses:  []string{"fd00::1"},
+				Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+				NodeName:   &nodeA,
+				TargetRef:  &corev1.ObjectReference{Kind: "Pod", Name: "coredns-1"},
+			},
+		},
+		Ports: []discoveryv1.EndpointPort{
+			{Protocol: &tcp, Port: &dnsTCPPort},
+			{Protocol: &udp, Port: &dnsUDPPort},
+		},
+	}
+	otherEndpointSlice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "other-abcde",
+			Namespace: "kube-system",
+			Labels: map[string]string{
+				discoveryv1.LabelServiceName: "other",
+			},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Endpoints: []discoveryv1.Endpoint{
+			{Addresses: []string{"10.0.0.4"}},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	informerFactory := informers.NewSharedInformerFactoryWithOptions(fake.NewClientset(endpointSlice, ipv6EndpointSlice, otherEndpointSlice), 0, informers.WithNamespace("kube-system"))
+
+	c := &Collector{
+		endpointSliceLister: informerFactory.Discovery().V1().EndpointSlices().Lister(),
+
+		service:   "coredns",
+		namespace: "kube-system",
+		perPod:    true,
+
+		nodeLocalAddress: "169.254.20.10:53",
+	}
+
+	informerFactory.Start(ctx.Done())
+	informerFactory.WaitForCacheSync(ctx.Done())
+
+	servers, err := c.listServers()
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	expectedServers := []server{
+		{resolver: resolverCluster, pod: "coredns-1", node: "node-a", ipFamily: ipFamilyIPv4, tcpAddress: "10.0.0.1:1053", udpAddress: "10.0.0.1:1053"},
+		{resolver: resolverCluster, pod: "coredns-1", node: "node-a", ipFamily: ipFamilyIPv6, tcpAddress: "[fd00::1]:1053", udpAddress: "[fd00::1]:1053"},
+		{resolver: resolverCluster, pod: "coredns-2", node: "node-b", ipFamily: ipFamilyIPv4, tcpAddress: "10.0.0.2:1053", udpAddress: "10.0.0.2:1053"},
+		{resolver: resolverNodeLocal, ipFamily: ipFamilyIPv4, tcpAddress: "169.254.20.10:53", udpAddress: "169.254.20.10:53"},
+	}
+
+	if !cmp.Equal(servers, expectedServers, cmp.AllowUnexported(server{})) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expectedServers, servers, cmp.AllowUnexported(server{})))
+	}
+}
@@ -0,0 +1,152 @@
+package dns
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	dnsclient "github.com/miekg/dns"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// ProtocolTCP resolves a Target over TCP.
+	ProtocolTCP = "tcp"
+	// ProtocolUDP resolves a Target over UDP.
+	ProtocolUDP = "udp"
+
+	// QueryTypeA queries the IPv4 addresses of a Target.
+	QueryTypeA = "A"
+	// QueryTypeAAAA queries the IPv6 addresses of a Target.
+	QueryTypeAAAA = "AAAA"
+	// QueryTypeCNAME queries the canonical name of a Target.
+	QueryTypeCNAME = "CNAME"
+	// QueryTypeMX queries the mail exchangers of a Target.
+	QueryTypeMX = "MX"
+	// QueryTypePTR queries the name a reverse lookup Target points to.
+	QueryTypePTR = "PTR"
+	// QueryTypeSRV queries the service records of a Target, e.g. of a
+	// headless Service.
+	QueryTypeSRV = "SRV"
+	// QueryTypeTXT queries the text records of a Target.
+	QueryTypeTXT = "TXT"
+)
+
+// queryTypes maps the supported query types to their DNS record types.
+var queryTypes = map[string]uint16{
+	QueryTypeA:     dnsclient.TypeA,
+	QueryTypeAAAA:  dnsclient.TypeAAAA,
+	QueryTypeCNAME: dnsclient.TypeCNAME,
+	QueryTypeMX:    dnsclient.TypeMX,
+	QueryTypePTR:   dnsclient.TypePTR,
+	QueryTypeSRV:   dnsclient.TypeSRV,
+	QueryTypeTXT:   dnsclient.TypeTXT,
+}
+
+// reservedLabelNames are the label names set by the Collector itself, which
+// Target labels must not override.
+var reservedLabelNames = []string{"host", "ip_family", "node", "pod", "qtype", "resolver"}
+
+// Target is a host the Collector resolves periodically.
+type Target struct {
+	// Host is the fully qualified name to resolve, e.g. "giantswarm.io.".
+	Host string
+	// Protocols are the transport protocols used to resolve Host, each of
+	// ProtocolTCP or ProtocolUDP.
+	Protocols []string
+	// QueryTypes are the record types queried for Host over each protocol,
+	// e.g. QueryTypeA and QueryTypeAAAA.
+	QueryTypes []string
+	// Interval is the time between two resolutions of Host.
+	Interval time.Duration
+	// Timeout is the maximum time a single resolution of Host may take.
+	Timeout time.Duration
+	// Expect optionally describes the answers Host is expected to resolve to.
+	Expect Expectation
+	// Labels are added as constant labels to the latency histograms of Host.
+	Labels map[string]string
+}
+
+// hasProtocol returns true if the Target is resolved over the given protocol.
+func (t Target) hasProtocol(proto string) bool {
+	for _, p := range t.Protocols {
+		if p == proto {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasQueryType returns true if the given record type is queried for the Target.
+func (t Target) hasQueryType(qtype string) bool {
+	for _, q := range t.QueryTypes {
+		if q == qtype {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateTargets returns an invalidConfigError describing the first invalid
+// Target, if any.
+func ValidateTargets(targets []Target) error {
+	if len(targets) == 0 {
+		return microerror.Maskf(invalidConfigError, "%T.Targets must not be empty", Config{})
+	}
+
+	hosts := map[string]bool{}
+	for i, t := range targets {
+		if t.Host == "" {
+			return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Host must not be empty", Config{}, i)
+		}
+		if hosts[t.Host] {
+			return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Host %#q must be unique", Config{}, i, t.Host)
+		}
+		hosts[t.Host] = true
+
+		if len(t.Protocols) == 0 {
+			return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Protocols must not be empty", Config{}, i)
+		}
+		for _, p := range t.Protocols {
+			if p != ProtocolTCP && p != ProtocolUDP {
+				return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Protocols must only contain %#q or %#q, got %#q", Config{}, i, ProtocolTCP, ProtocolUDP, p)
+			}
+		}
+
+		if len(t.QueryTypes) == 0 {
+			return microerror.Maskf(invalidConfigError, "%T.Targets[%d].QueryTypes must not be empty", Config{}, i)
+		}
+		for _, q := range t.QueryTypes {
+			if _, ok := queryTypes[q]; !ok {
+				return microerror.Maskf(invalidConfigError, "%T.Targets[%d].QueryTypes contains unsupported query type %#q", Config{}, i, q)
+			}
+		}
+
+		err := t.Expect.validate(fmt.Sprintf("%T.Targets[%d].Expect", Config{}, i))
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		if t.Interval <= 0 {
+			return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Interval must be greater than zero", Config{}, i)
+		}
+		if t.Timeout <= 0 {
+			return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Timeout must be greater than zero", Config{}, i)
+		}
+
+		for name := range t.Labels {
+			if !model.LegacyValidation.IsValidLabelName(name) {
+				return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Labels contains invalid label name %#q", Config{}, i, name)
+			}
+			for _, reserved := range reservedLabelNames {
+				if name == reserved {
+					return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Labels must not contain reserved label name %#q", Config{}, i, name)
+				}
+			}
+		}
+	}
+
+	return nil
+}
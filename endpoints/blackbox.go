@@ -2,11 +2,20 @@ package endpoints
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
 	kitendpoint "github.com/go-kit/kit/endpoint"
 	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+
+	"github.com/giantswarm/net-exporter/dns"
 )
 
 const (
@@ -15,36 +24,182 @@ const (
 	// BlackboxName identifies the endpoint. It is aligned to the package path.
 	BlackboxName = "blackbox"
 	// BlackboxPath is the HTTP request path this endpoint is registered for.
-	BlackboxPath = "/blackbox"
+	BlackboxPath = "/probe"
+	// BlackboxLegacyPath is the HTTP request path this endpoint was registered
+	// for before, and is still served for compatibility.
+	BlackboxLegacyPath = "/blackbox"
+
+	// scrapeTimeoutHeader is set by Prometheus to the scrape timeout, in seconds.
+	scrapeTimeoutHeader = "X-Prometheus-Scrape-Timeout-Seconds"
+	// scrapeTimeoutOffset is subtracted from the scrape timeout, so that the
+	// probe finishes before Prometheus gives up on the scrape.
+	scrapeTimeoutOffset = 500 * time.Millisecond
 )
 
+// BlackboxConfig provides the necessary configuration for creating a Blackbox.
 type BlackboxConfig struct {
-}
+	DNSCollector *dns.Collector
+	Logger       micrologger.Logger
 
-func NewBlackbox(config BlackboxConfig) (*Blackbox, error) {
-	return &Blackbox{}, nil
+	// Path is the HTTP request path the Blackbox is registered for. It
+	// defaults to BlackboxPath.
+	Path string
+	// Timeout is the maximum time a single probe may take. Shorter scrape
+	// timeouts announced by Prometheus take precedence.
+	Timeout time.Duration
 }
 
+// Blackbox is a multi-target probe endpoint in the style of the Prometheus
+// blackbox_exporter. A request like
+// /probe?module=dns_udp&target=10.96.0.10&query_name=example.com runs a single
+// probe from the network namespace of the node, and responds with the metrics
+// of that probe.
 type Blackbox struct {
+	logger micrologger.Logger
+
+	modules map[string]module
+	path    string
+	timeout time.Duration
+}
+
+type probeRequest struct {
+	module  string
+	target  string
+	query   url.Values
+	timeout time.Duration
+}
+
+type probeResponse struct {
+	// err is set if the request could not be served, as opposed to the probe
+	// failing, which is reported by the probe_success metric.
+	err      error
+	registry *prometheus.Registry
+}
+
+// NewBlackbox creates a Blackbox, given a BlackboxConfig.
+func NewBlackbox(config BlackboxConfig) (*Blackbox, error) {
+	if config.DNSCollector == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.DNSCollector must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.Timeout <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Timeout must be greater than zero", config)
+	}
+
+	if config.Path == "" {
+		config.Path = BlackboxPath
+	}
+
+	b := &Blackbox{
+		logger: config.Logger,
+
+		modules: newModules(config.DNSCollector, config.Logger),
+		path:    config.Path,
+		timeout: config.Timeout,
+	}
+
+	return b, nil
 }
 
 func (b *Blackbox) Decoder() kithttp.DecodeRequestFunc {
 	return func(ctx context.Context, r *http.Request) (any, error) {
-		return nil, nil
+		query := r.URL.Query()
+
+		request := probeRequest{
+			module:  query.Get("module"),
+			target:  query.Get("target"),
+			query:   query,
+			timeout: b.timeout,
+		}
+
+		if v := r.Header.Get(scrapeTimeoutHeader); v != "" {
+			seconds, err := strconv.ParseFloat(v, 64)
+			if err == nil {
+				scrapeTimeout := time.Duration(seconds*float64(time.Second)) - scrapeTimeoutOffset
+				if scrapeTimeout > 0 && scrapeTimeout < request.timeout {
+					request.timeout = scrapeTimeout
+				}
+			}
+		}
+
+		return request, nil
 	}
 }
 
 func (b *Blackbox) Encoder() kithttp.EncodeResponseFunc {
 	return func(ctx context.Context, w http.ResponseWriter, response any) error {
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		_, err := w.Write([]byte("ok"))
-		return microerror.Mask(err)
+		r := response.(probeResponse)
+
+		if r.err != nil {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			_, err := w.Write([]byte(microerror.Pretty(r.err, false)))
+			return microerror.Mask(err)
+		}
+
+		metricFamilies, err := r.registry.Gather()
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		format := expfmt.NewFormat(expfmt.TypeTextPlain)
+		w.Header().Set("Content-Type", string(format))
+
+		encoder := expfmt.NewEncoder(w, format)
+		for _, mf := range metricFamilies {
+			err := encoder.Encode(mf)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+
+		return nil
 	}
 }
 
 func (b *Blackbox) Endpoint() kitendpoint.Endpoint {
 	return func(ctx context.Context, request any) (any, error) {
-		return nil, nil
+		r := request.(probeRequest)
+
+		m, ok := b.modules[r.module]
+		if !ok {
+			return probeResponse{err: microerror.Maskf(invalidProbeRequestError, "unknown module %#q", r.module)}, nil
+		}
+		if r.target == "" {
+			return probeResponse{err: microerror.Maskf(invalidProbeRequestError, "target must not be empty")}, nil
+		}
+
+		registry := prometheus.NewRegistry()
+
+		probeSuccess := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "probe_success",
+			Help: "Whether the probe was successful.",
+		})
+		probeDuration := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "probe_duration_seconds",
+			Help: "Duration of the probe.",
+		})
+		registry.MustRegister(probeSuccess, probeDuration)
+
+		ctx, cancel := context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+
+		start := time.Now()
+		err := m(ctx, r.target, r.query, r.timeout, registry)
+		probeDuration.Set(time.Since(start).Seconds())
+
+		if IsInvalidProbeRequest(err) {
+			return probeResponse{err: err}, nil
+		} else if err != nil {
+			b.logger.Log("level", "debug", "message", fmt.Sprintf("probe of target %#q with module %#q failed", r.target, r.module), "stack", microerror.JSON(err))
+		} else {
+			probeSuccess.Set(1)
+		}
+
+		return probeResponse{registry: registry}, nil
 	}
 }
 
@@ -61,5 +216,5 @@ func (b *Blackbox) Name() string {
 }
 
 func (b *Blackbox) Path() string {
-	return BlackboxPath
+	return b.path
 }
@@ -0,0 +1,142 @@
+package endpoints
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/giantswarm/net-exporter/dns"
+)
+
+func Test_Blackbox_Decoder(t *testing.T) {
+	testCases := []struct {
+		name            string
+		inputHeader     string
+		expectedTimeout time.Duration
+	}{
+		{
+			name:            "case 0: no scrape timeout uses the configured timeout",
+			inputHeader:     "",
+			expectedTimeout: 5 * time.Second,
+		},
+		{
+			name:            "case 1: shorter scrape timeout takes precedence",
+			inputHeader:     "3",
+			expectedTimeout: 2500 * time.Millisecond,
+		},
+		{
+			name:            "case 2: longer scrape timeout is ignored",
+			inputHeader:     "10",
+			expectedTimeout: 5 * time.Second,
+		},
+		{
+			name:            "case 3: scrape timeout below the offset is ignored",
+			inputHeader:     "0.2",
+			expectedTimeout: 5 * time.Second,
+		},
+		{
+			name:            "case 4: malformed scrape timeout is ignored",
+			inputHeader:     "soon",
+			expectedTimeout: 5 * time.Second,
+		},
+	}
+
+	b := &Blackbox{
+		timeout: 5 * time.Second,
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			r := httptest.NewRequest(BlackboxMethod, BlackboxPath+"?module=tcp&target=10.0.0.1:443&query_name=example.com", nil)
+			if tc.inputHeader != "" {
+				r.Header.Set(scrapeTimeoutHeader, tc.inputHeader)
+			}
+
+			request, err := b.Decoder()(context.Background(), r)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			p := request.(probeRequest)
+			if p.module != ModuleTCP {
+				t.Fatalf("module == %q, want %q", p.module, ModuleTCP)
+			}
+			if p.target != "10.0.0.1:443" {
+				t.Fatalf("target == %q, want %q", p.target, "10.0.0.1:443")
+			}
+			if p.query.Get("query_name") != "example.com" {
+				t.Fatalf("query_name == %q, want %q", p.query.Get("query_name"), "example.com")
+			}
+			if p.timeout != tc.expectedTimeout {
+				t.Fatalf("timeout == %v, want %v", p.timeout, tc.expectedTimeout)
+			}
+		})
+	}
+}
+
+func Test_Blackbox_Path(t *testing.T) {
+	testCases := []struct {
+		name         string
+		inputPath    string
+		expectedPath string
+	}{
+		{
+			name:         "case 0: empty path defaults to the probe path",
+			inputPath:    "",
+			expectedPath: BlackboxPath,
+		},
+		{
+			name:         "case 1: legacy path is kept",
+			inputPath:    BlackboxLegacyPath,
+			expectedPath: BlackboxLegacyPath,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			b, err := NewBlackbox(BlackboxConfig{
+				DNSCollector: &dns.Collector{},
+				Logger:       microloggertest.New(),
+
+				Path:    tc.inputPath,
+				Timeout: time.Second,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if b.Path() != tc.expectedPath {
+				t.Fatalf("path == %q, want %q", b.Path(), tc.expectedPath)
+			}
+		})
+	}
+}
+
+func Test_DNSModule_queryName(t *testing.T) {
+	m := newDNSModule(&dns.Collector{}, dns.ProtocolUDP)
+
+	err := m(context.Background(), "10.96.0.10", url.Values{}, time.Second, prometheus.NewRegistry())
+	if !IsInvalidProbeRequest(err) {
+		t.Fatalf("error == %#v, want invalid probe request error", err)
+	}
+}
+
+func Test_DNSModule_queryType(t *testing.T) {
+	m := newDNSModule(&dns.Collector{}, dns.ProtocolUDP)
+
+	query := url.Values{
+		"query_name": {"giantswarm.io"},
+		"query_type": {"NS"},
+	}
+
+	err := m(context.Background(), "10.96.0.10", query, time.Second, prometheus.NewRegistry())
+	if !IsInvalidProbeRequest(err) {
+		t.Fatalf("error == %#v, want invalid probe request error", err)
+	}
+}
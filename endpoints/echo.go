@@ -0,0 +1,98 @@
+package endpoints
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	kitendpoint "github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/giantswarm/net-exporter/network"
+)
+
+const (
+	// EchoMethod is the HTTP method this endpoint is register for.
+	EchoMethod = "POST"
+	// EchoName identifies the endpoint. It is aligned to the package path.
+	EchoName = "echo"
+	// EchoPath is the HTTP request path this endpoint is registered for.
+	EchoPath = network.EchoPath
+)
+
+// EchoConfig provides the necessary configuration for creating an Echo.
+type EchoConfig struct {
+	Logger micrologger.Logger
+}
+
+// Echo answers the echo requests the network collectors of the other
+// net-exporters send on the connections they dial, so that they measure a
+// full round trip with payload, and verify that they reached a net-exporter.
+type Echo struct {
+	logger micrologger.Logger
+}
+
+type echoRequest struct {
+	payload []byte
+}
+
+// NewEcho creates an Echo, given an EchoConfig.
+func NewEcho(config EchoConfig) (*Echo, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	e := &Echo{
+		logger: config.Logger,
+	}
+
+	return e, nil
+}
+
+func (e *Echo) Decoder() kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (any, error) {
+		payload, err := io.ReadAll(io.LimitReader(r.Body, network.MaxEchoPayload))
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return echoRequest{payload: payload}, nil
+	}
+}
+
+func (e *Echo) Encoder() kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response any) error {
+		w.Header().Set("Content-Type", "application/octet-stream")
+
+		_, err := w.Write(response.([]byte))
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	}
+}
+
+func (e *Echo) Endpoint() kitendpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		return network.EchoResponse(request.(echoRequest).payload), nil
+	}
+}
+
+func (e *Echo) Method() string {
+	return EchoMethod
+}
+
+func (e *Echo) Middlewares() []kitendpoint.Middleware {
+	return []kitendpoint.Middleware{}
+}
+
+func (e *Echo) Name() string {
+	return EchoName
+}
+
+func (e *Echo) Path() string {
+	return EchoPath
+}
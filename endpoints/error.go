@@ -0,0 +1,32 @@
+package endpoints
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var invalidProbeRequestError = &microerror.Error{
+	Kind: "invalidProbeRequestError",
+}
+
+// IsInvalidProbeRequest asserts invalidProbeRequestError.
+func IsInvalidProbeRequest(err error) bool {
+	return microerror.Cause(err) == invalidProbeRequestError
+}
+
+var probeFailedError = &microerror.Error{
+	Kind: "probeFailedError",
+}
+
+// IsProbeFailed asserts probeFailedError.
+func IsProbeFailed(err error) bool {
+	return microerror.Cause(err) == probeFailedError
+}
@@ -0,0 +1,188 @@
+package endpoints
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/beevik/ntp"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	dnsclient "github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/giantswarm/net-exporter/dns"
+)
+
+const (
+	// ModuleDNSTCP resolves the records of the query_type parameter, A by
+	// default, of the query_name parameter over TCP against the target DNS
+	// server.
+	ModuleDNSTCP = "dns_tcp"
+	// ModuleDNSUDP resolves the records of the query_type parameter, A by
+	// default, of the query_name parameter over UDP against the target DNS
+	// server.
+	ModuleDNSUDP = "dns_udp"
+	// ModuleNTP syncs with the target NTP server.
+	ModuleNTP = "ntp"
+	// ModuleTCP dials the target host:port over TCP.
+	ModuleTCP = "tcp"
+
+	// dnsPort is the port of target DNS servers given without one.
+	dnsPort = "53"
+)
+
+// module runs a single probe of the given target, registering its module
+// specific metrics with the given registry. Module specific parameters are
+// taken from the given query. It returns an error if the probe failed, or an
+// invalidProbeRequestError if the target or the query is not valid for the
+// module.
+type module func(ctx context.Context, target string, query url.Values, timeout time.Duration, registry *prometheus.Registry) error
+
+func newModules(dnsCollector *dns.Collector, logger micrologger.Logger) map[string]module {
+	return map[string]module{
+		ModuleDNSTCP: newDNSModule(dnsCollector, dns.ProtocolTCP),
+		ModuleDNSUDP: newDNSModule(dnsCollector, dns.ProtocolUDP),
+		ModuleNTP:    probeNTP,
+		ModuleTCP:    newTCPModule(logger),
+	}
+}
+
+func newDNSModule(dnsCollector *dns.Collector, proto string) module {
+	return func(ctx context.Context, target string, query url.Values, timeout time.Duration, registry *prometheus.Registry) error {
+		queryName := query.Get("query_name")
+		if queryName == "" {
+			return microerror.Maskf(invalidProbeRequestError, "query_name must not be empty")
+		}
+		queryType := query.Get("query_type")
+		if queryType == "" {
+			queryType = dns.QueryTypeA
+		}
+
+		server := target
+		if _, _, err := net.SplitHostPort(target); err != nil {
+			server = net.JoinHostPort(target, dnsPort)
+		}
+
+		answerRRs := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "probe_dns_answer_rrs",
+			Help: "Number of records in the answer section of the DNS response.",
+		})
+		authorityRRs := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "probe_dns_authority_rrs",
+			Help: "Number of records in the authority section of the DNS response.",
+		})
+		additionalRRs := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "probe_dns_additional_rrs",
+			Help: "Number of records in the additional section of the DNS response.",
+		})
+		registry.MustRegister(answerRRs, authorityRRs, additionalRRs)
+
+		msg, err := dnsCollector.Resolve(ctx, proto, server, dnsclient.Fqdn(queryName), queryType, timeout)
+		if dns.IsInvalidConfig(err) {
+			return microerror.Maskf(invalidProbeRequestError, "%s", err)
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+
+		answerRRs.Set(float64(len(msg.Answer)))
+		authorityRRs.Set(float64(len(msg.Ns)))
+		additionalRRs.Set(float64(len(msg.Extra)))
+
+		if len(msg.Answer) == 0 {
+			return microerror.Maskf(probeFailedError, "no answer for host %#q from server %#q, rcode %s", queryName, server, dnsclient.RcodeToString[msg.Rcode])
+		}
+
+		return nil
+	}
+}
+
+func probeNTP(ctx context.Context, target string, query url.Values, timeout time.Duration, registry *prometheus.Registry) error {
+	offset := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_ntp_offset_seconds",
+		Help: "Estimated offset of the local clock relative to the NTP server.",
+	})
+	rtt := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_ntp_rtt_seconds",
+		Help: "Round-trip time to the NTP server.",
+	})
+	stratum := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_ntp_stratum",
+		Help: "Stratum of the NTP server.",
+	})
+	registry.MustRegister(offset, rtt, stratum)
+
+	// The NTP client does not take a context, so the deadline of the probe
+	// bounds its timeout, and the dial is bound to the context.
+	if d, ok := ctx.Deadline(); ok && time.Until(d) < timeout {
+		timeout = time.Until(d)
+	}
+
+	options := ntp.QueryOptions{
+		Timeout: timeout,
+		Dialer: func(localAddress, remoteAddress string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "udp", remoteAddress)
+		},
+	}
+
+	response, err := ntp.QueryWithOptions(target, options)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	offset.Set(response.ClockOffset.Seconds())
+	rtt.Set(response.RTT.Seconds())
+	stratum.Set(float64(response.Stratum))
+
+	err = response.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func newTCPModule(logger micrologger.Logger) module {
+	return func(ctx context.Context, target string, query url.Values, timeout time.Duration, registry *prometheus.Registry) error {
+		return probeTCP(ctx, logger, target, timeout, registry)
+	}
+}
+
+func probeTCP(ctx context.Context, logger micrologger.Logger, target string, timeout time.Duration, registry *prometheus.Registry) error {
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		return microerror.Maskf(invalidProbeRequestError, "target must be in host:port form, got %#q", target)
+	}
+
+	ipProtocol := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_ip_protocol",
+		Help: "IP protocol version of the connection, 4 or 6.",
+	})
+	registry.MustRegister(ipProtocol)
+
+	dialer := &net.Dialer{
+		Timeout: timeout,
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", target)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			logger.Log("level", "error", "message", fmt.Sprintf("failed to close connection for target %#q", target), "stack", microerror.JSON(err))
+		}
+	}()
+
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		if addr.IP.To4() != nil {
+			ipProtocol.Set(4)
+		} else {
+			ipProtocol.Set(6)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,448 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/exporterkit/histogramvec"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/giantswarm/net-exporter/scheduler"
+)
+
+const (
+	namespace = "http"
+
+	bucketStart  = 0.001
+	bucketFactor = 2
+	numBuckets   = 12
+
+	// The phases of a request, as traced by httptrace. phaseFirstByte is the
+	// time from writing the request to the first byte of the response.
+	phaseDNS       = "dns"
+	phaseConnect   = "connect"
+	phaseTLS       = "tls"
+	phaseFirstByte = "first_byte"
+
+	// maxBodySize is the number of bytes of a response body read at most, so
+	// that large or endless bodies do not keep a request busy.
+	maxBodySize = 1 << 20
+)
+
+// Config provides the necessary configuration for creating a Collector.
+type Config struct {
+	Logger micrologger.Logger
+
+	Targets []Target
+}
+
+// Collector implements the Collector interface, exposing HTTP request
+// information.
+type Collector struct {
+	logger micrologger.Logger
+	// transport is used for all requests. Connections are not reused, so
+	// that every request is timed from the DNS lookup on.
+	transport *http.Transport
+
+	// targets holds the configured Targets, keyed by URL.
+	targets map[string]Target
+	// results holds the result of the last request of each URL.
+	results map[string]result
+	mutex   sync.Mutex
+
+	latencyHistogramVec  *histogramvec.HistogramVec
+	latencyHistogramDesc *prometheus.Desc
+
+	errorCount        prometheus.Counter
+	requestErrorCount *prometheus.CounterVec
+}
+
+// result is the outcome of a request. Only success is set if the request
+// failed without a response.
+type result struct {
+	success    bool
+	statusCode int
+	// contentLength is the length of the body read, at most maxBodySize, or
+	// of the Content-Length header of HEAD requests. It is -1 if unknown.
+	contentLength int64
+	phases        map[string]time.Duration
+}
+
+// New creates a Collector, given a Config.
+func New(config Config) (*Collector, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	err := ValidateTargets(config.Targets)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	targets := map[string]Target{}
+	for _, t := range config.Targets {
+		targets[t.URL] = t
+	}
+
+	var latencyHistogramVec *histogramvec.HistogramVec
+	{
+		c := histogramvec.Config{
+			BucketLimits: prometheus.ExponentialBuckets(bucketStart, bucketFactor, numBuckets),
+		}
+		latencyHistogramVec, err = histogramvec.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	errorCount := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: prometheus.BuildFQName(namespace, "", "error_total"),
+		Help: "Total number of internal errors.",
+	})
+	requestErrorCount := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "", "request_error_total"),
+			Help: "Total number of HTTP requests failing without a response.",
+		},
+		[]string{"url"},
+	)
+
+	prometheus.MustRegister(errorCount)
+	prometheus.MustRegister(requestErrorCount)
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = true
+
+	collector := &Collector{
+		logger:    config.Logger,
+		transport: transport,
+
+		targets: targets,
+		results: map[string]result{},
+
+		latencyHistogramVec:  latencyHistogramVec,
+		latencyHistogramDesc: newLatencyHistogramDesc(nil),
+
+		errorCount:        errorCount,
+		requestErrorCount: requestErrorCount,
+	}
+
+	return collector, nil
+}
+
+// Describe implements the Describe method of the Collector interface.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.latencyHistogramDesc
+	ch <- newSuccessDesc(nil)
+	ch <- newStatusCodeDesc(nil)
+	ch <- newContentLengthDesc(nil)
+	ch <- newPhaseDurationDesc(nil)
+}
+
+// Jobs implements the Prober interface of the scheduler package.
+func (c *Collector) Jobs() []scheduler.Job {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var jobs []scheduler.Job
+
+	for u, t := range c.targets {
+		jobs = append(jobs, scheduler.Job{
+			Name:     fmt.Sprintf("%s/%s", namespace, u),
+			Interval: t.Interval,
+			Run: func(ctx context.Context) {
+				c.probe(ctx, u)
+			},
+		})
+	}
+
+	return jobs
+}
+
+// Reconfigure replaces the Targets of the Collector, and removes the metrics
+// of URLs which are not requested anymore. The jobs of the Collector change
+// accordingly, and must be synced with the scheduler.
+func (c *Collector) Reconfigure(targets []Target) error {
+	err := ValidateTargets(targets)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	newTargets := map[string]Target{}
+	var urls []string
+	for _, t := range targets {
+		newTargets[t.URL] = t
+		urls = append(urls, t.URL)
+	}
+
+	for u, t := range c.targets {
+		newTarget, ok := newTargets[u]
+		if !ok {
+			c.requestErrorCount.DeleteLabelValues(u)
+		}
+		// A result of another method must not be exposed as the result of
+		// the new one.
+		if !ok || newTarget.Method != t.Method {
+			delete(c.results, u)
+		}
+	}
+
+	c.latencyHistogramVec.Ensure(urls)
+
+	c.targets = newTargets
+
+	return nil
+}
+
+// Collect implements the Collect method of the Collector interface.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for u, histogram := range c.latencyHistogramVec.Histograms() {
+		t, ok := c.targets[u]
+		if !ok {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstHistogram(
+			newLatencyHistogramDesc(t.Labels),
+			histogram.Count(), histogram.Sum(), histogram.Buckets(),
+			u,
+		)
+	}
+
+	for u, r := range c.results {
+		t, ok := c.targets[u]
+		if !ok {
+			continue
+		}
+
+		success := 0.0
+		if r.success {
+			success = 1
+		}
+		ch <- prometheus.MustNewConstMetric(newSuccessDesc(t.Labels), prometheus.GaugeValue, success, u)
+
+		if r.statusCode == 0 {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(newStatusCodeDesc(t.Labels), prometheus.GaugeValue, float64(r.statusCode), u)
+		if r.contentLength >= 0 {
+			ch <- prometheus.MustNewConstMetric(newContentLengthDesc(t.Labels), prometheus.GaugeValue, float64(r.contentLength), u)
+		}
+		for phase, d := range r.phases {
+			ch <- prometheus.MustNewConstMetric(newPhaseDurationDesc(t.Labels), prometheus.GaugeValue, d.Seconds(), u, phase)
+		}
+	}
+}
+
+func (c *Collector) probe(ctx context.Context, u string) {
+	c.mutex.Lock()
+	t, ok := c.targets[u]
+	c.mutex.Unlock()
+	if !ok {
+		return
+	}
+
+	r, elapsed, err := c.request(ctx, t)
+	if err != nil {
+		c.logger.Log("level", "error", "message", fmt.Sprintf("failed to request url %#q", u), "stack", microerror.JSON(err))
+		c.requestErrorCount.WithLabelValues(u).Inc()
+	}
+
+	c.mutex.Lock()
+	if _, ok := c.targets[u]; ok {
+		c.results[u] = r
+	}
+	c.mutex.Unlock()
+
+	if err != nil {
+		return
+	}
+
+	err = c.latencyHistogramVec.Add(u, elapsed.Seconds())
+	if err != nil {
+		c.logger.Log("level", "error", "message", fmt.Sprintf("failed to update latency histogram for url %#q", u), "stack", microerror.JSON(err))
+		c.errorCount.Inc()
+		return
+	}
+}
+
+// request requests the URL of the given Target, and returns the result and the
+// duration of the request, including reading the body up to maxBodySize.
+// Redirects are not followed, so that the result describes a single request,
+// and it is successful if the status code is below 400.
+func (c *Collector) request(ctx context.Context, t Target) (result, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	tracer := newPhaseTracer()
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, tracer.clientTrace()), t.Method, t.URL, nil)
+	if err != nil {
+		return result{}, 0, microerror.Mask(err)
+	}
+
+	client := &http.Client{
+		Transport: c.transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	start := time.Now()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return result{}, 0, microerror.Mask(err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			c.logger.Log("level", "error", "message", fmt.Sprintf("failed to close response body for url %#q", t.URL), "stack", microerror.JSON(err))
+		}
+	}()
+
+	n, err := io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return result{}, 0, microerror.Mask(err)
+	}
+
+	elapsed := time.Since(start)
+
+	r := result{
+		success:       resp.StatusCode < http.StatusBadRequest,
+		statusCode:    resp.StatusCode,
+		contentLength: n,
+		phases:        tracer.durations(),
+	}
+	if t.Method == MethodHead {
+		r.contentLength = resp.ContentLength
+	}
+
+	return r, elapsed, nil
+}
+
+// phaseTracer records the duration of the phases of a request.
+type phaseTracer struct {
+	mutex  sync.Mutex
+	starts map[string]time.Time
+	phases map[string]time.Duration
+}
+
+func newPhaseTracer() *phaseTracer {
+	return &phaseTracer{
+		starts: map[string]time.Time{},
+		phases: map[string]time.Duration{},
+	}
+}
+
+func (p *phaseTracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { p.start(phaseDNS) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { p.done(phaseDNS) },
+		ConnectStart:         func(string, string) { p.start(phaseConnect) },
+		ConnectDone:          func(string, string, error) { p.done(phaseConnect) },
+		TLSHandshakeStart:    func() { p.start(phaseTLS) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { p.done(phaseTLS) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { p.start(phaseFirstByte) },
+		GotFirstResponseByte: func() { p.done(phaseFirstByte) },
+	}
+}
+
+func (p *phaseTracer) start(phase string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.starts[phase] = time.Now()
+}
+
+func (p *phaseTracer) done(phase string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if start, ok := p.starts[phase]; ok {
+		p.phases[phase] = time.Since(start)
+	}
+}
+
+// durations returns the durations of the phases which took place. Phases may
+// be skipped, e.g. the DNS lookup of IP addresses.
+func (p *phaseTracer) durations() map[string]time.Duration {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	phases := map[string]time.Duration{}
+	for phase, d := range p.phases {
+		phases[phase] = d
+	}
+
+	return phases
+}
+
+// newLatencyHistogramDesc returns the descriptor of the latency histograms,
+// with the given constant labels.
+func newLatencyHistogramDesc(constLabels map[string]string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "latency_seconds"),
+		"Histogram of latency of HTTP requests, including reading the body.",
+		[]string{"url"},
+		constLabels,
+	)
+}
+
+// newSuccessDesc returns the descriptor of the success gauge, with the given
+// constant labels.
+func newSuccessDesc(constLabels map[string]string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "success"),
+		"Whether the last HTTP request of the URL got a status code below 400.",
+		[]string{"url"},
+		constLabels,
+	)
+}
+
+// newStatusCodeDesc returns the descriptor of the status code gauge, with the
+// given constant labels.
+func newStatusCodeDesc(constLabels map[string]string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "status_code"),
+		"Status code of the last HTTP response of the URL.",
+		[]string{"url"},
+		constLabels,
+	)
+}
+
+// newContentLengthDesc returns the descriptor of the content length gauge,
+// with the given constant labels.
+func newContentLengthDesc(constLabels map[string]string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "content_length_bytes"),
+		"Length of the body of the last HTTP response of the URL.",
+		[]string{"url"},
+		constLabels,
+	)
+}
+
+// newPhaseDurationDesc returns the descriptor of the phase duration gauges,
+// with the given constant labels.
+func newPhaseDurationDesc(constLabels map[string]string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "phase_duration_seconds"),
+		"Duration of the phases of the last HTTP request of the URL: dns, connect, tls and first_byte.",
+		[]string{"url", "phase"},
+		constLabels,
+	)
+}
@@ -0,0 +1,143 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Collector_request(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(make([]byte, 2*maxBodySize))
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/missing", http.StatusFound)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(mux)
+	defer tlsServer.Close()
+
+	testCases := []struct {
+		name                  string
+		inputURL              string
+		inputMethod           string
+		expectedSuccess       bool
+		expectedStatusCode    int
+		expectedContentLength int64
+		expectedPhases        []string
+	}{
+		{
+			name:                  "case 0: GET reads the body",
+			inputURL:              server.URL + "/ok",
+			inputMethod:           MethodGet,
+			expectedSuccess:       true,
+			expectedStatusCode:    http.StatusOK,
+			expectedContentLength: 5,
+			expectedPhases:        []string{phaseConnect, phaseFirstByte},
+		},
+		{
+			name:                  "case 1: HEAD uses the Content-Length header",
+			inputURL:              server.URL + "/ok",
+			inputMethod:           MethodHead,
+			expectedSuccess:       true,
+			expectedStatusCode:    http.StatusOK,
+			expectedContentLength: 5,
+			expectedPhases:        []string{phaseConnect, phaseFirstByte},
+		},
+		{
+			name:                  "case 2: client errors are not successful",
+			inputURL:              server.URL + "/missing",
+			inputMethod:           MethodGet,
+			expectedSuccess:       false,
+			expectedStatusCode:    http.StatusNotFound,
+			expectedContentLength: 19,
+			expectedPhases:        []string{phaseConnect, phaseFirstByte},
+		},
+		{
+			name:                  "case 3: redirects are not followed",
+			inputURL:              server.URL + "/redirect",
+			inputMethod:           MethodHead,
+			expectedSuccess:       true,
+			expectedStatusCode:    http.StatusFound,
+			expectedContentLength: -1,
+			expectedPhases:        []string{phaseConnect, phaseFirstByte},
+		},
+		{
+			name:                  "case 4: HTTPS includes the TLS handshake",
+			inputURL:              tlsServer.URL + "/ok",
+			inputMethod:           MethodGet,
+			expectedSuccess:       true,
+			expectedStatusCode:    http.StatusOK,
+			expectedContentLength: 5,
+			expectedPhases:        []string{phaseConnect, phaseFirstByte, phaseTLS},
+		},
+		{
+			name:                  "case 5: GET reads the body up to its maximum size",
+			inputURL:              server.URL + "/large",
+			inputMethod:           MethodGet,
+			expectedSuccess:       true,
+			expectedStatusCode:    http.StatusOK,
+			expectedContentLength: maxBodySize,
+			expectedPhases:        []string{phaseConnect, phaseFirstByte},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			transport := tlsServer.Client().Transport.(*http.Transport).Clone()
+			transport.DisableKeepAlives = true
+
+			c := &Collector{
+				logger:    microloggertest.New(),
+				transport: transport,
+			}
+
+			target := Target{
+				URL:     tc.inputURL,
+				Method:  tc.inputMethod,
+				Timeout: 5 * time.Second,
+			}
+
+			r, _, err := c.request(context.Background(), target)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if r.success != tc.expectedSuccess {
+				t.Fatalf("success == %v, want %v", r.success, tc.expectedSuccess)
+			}
+			if r.statusCode != tc.expectedStatusCode {
+				t.Fatalf("statusCode == %d, want %d", r.statusCode, tc.expectedStatusCode)
+			}
+			if r.contentLength != tc.expectedContentLength {
+				t.Fatalf("contentLength == %d, want %d", r.contentLength, tc.expectedContentLength)
+			}
+
+			var phases []string
+			for phase := range r.phases {
+				phases = append(phases, phase)
+			}
+			sort.Strings(phases)
+
+			if !cmp.Equal(phases, tc.expectedPhases) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedPhases, phases))
+			}
+		})
+	}
+}
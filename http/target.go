@@ -0,0 +1,75 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// MethodGet probes a Target with a GET request, reading the body up to 1 MiB.
+	MethodGet = http.MethodGet
+	// MethodHead probes a Target with a HEAD request.
+	MethodHead = http.MethodHead
+)
+
+// reservedLabelNames are the label names set by the Collector itself, which
+// Target labels must not override.
+var reservedLabelNames = []string{"phase", "url"}
+
+// Target is a URL the Collector requests periodically.
+type Target struct {
+	// URL is the http or https URL to request.
+	URL string
+	// Method is the method of the request, MethodGet or MethodHead.
+	Method string
+	// Interval is the time between two requests of URL.
+	Interval time.Duration
+	// Timeout is the maximum time a single request of URL may take.
+	Timeout time.Duration
+	// Labels are added as constant labels to the metrics of URL.
+	Labels map[string]string
+}
+
+// ValidateTargets returns an invalidConfigError describing the first invalid
+// Target, if any.
+func ValidateTargets(targets []Target) error {
+	urls := map[string]bool{}
+	for i, t := range targets {
+		u, err := url.Parse(t.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return microerror.Maskf(invalidConfigError, "%T.Targets[%d].URL must be an absolute http or https URL, got %#q", Config{}, i, t.URL)
+		}
+		if urls[t.URL] {
+			return microerror.Maskf(invalidConfigError, "%T.Targets[%d].URL %#q must be unique", Config{}, i, t.URL)
+		}
+		urls[t.URL] = true
+
+		if t.Method != MethodGet && t.Method != MethodHead {
+			return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Method must be %#q or %#q, got %#q", Config{}, i, MethodGet, MethodHead, t.Method)
+		}
+
+		if t.Interval <= 0 {
+			return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Interval must be greater than zero", Config{}, i)
+		}
+		if t.Timeout <= 0 {
+			return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Timeout must be greater than zero", Config{}, i)
+		}
+
+		for name := range t.Labels {
+			if !model.LegacyValidation.IsValidLabelName(name) {
+				return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Labels contains invalid label name %#q", Config{}, i, name)
+			}
+			for _, reserved := range reservedLabelNames {
+				if name == reserved {
+					return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Labels must not contain reserved label name %#q", Config{}, i, name)
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"net"
@@ -14,39 +15,101 @@ import (
 	"github.com/giantswarm/micrologger"
 	dnsclient "github.com/miekg/dns"
 	"github.com/prometheus/client_golang/prometheus"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
 	"k8s.io/client-go/rest"
 
 	"github.com/giantswarm/microerror"
 
+	"github.com/giantswarm/net-exporter/config"
 	"github.com/giantswarm/net-exporter/dns"
 	"github.com/giantswarm/net-exporter/endpoints"
+	"github.com/giantswarm/net-exporter/http"
 	"github.com/giantswarm/net-exporter/network"
 	"github.com/giantswarm/net-exporter/ntp"
+	"github.com/giantswarm/net-exporter/scheduler"
 )
 
 var (
-	disableDNSTCPCheck bool
-	hosts              string
-	dnsService         string
-	dnsNamespace       string
-	namespace          string
-	ntpServers         string
-	port               string
-	service            string
-	timeout            time.Duration
+	configFile           string
+	configReloadInterval time.Duration
+	disableDNSTCPCheck   bool
+	hosts                string
+	httpInterval         time.Duration
+	httpMethod           string
+	httpURLs             string
+	dnsInterval          time.Duration
+	dnsService           string
+	dnsNamespace         string
+	dnsNodeLocalAddress  string
+	dnsNodeLocalPort     int
+	dnsPerPod            bool
+	dnsQueryTypes        string
+	namespace            string
+	networkICMP          bool
+	networkICMPCount     int
+	networkInterval      time.Duration
+	networkMeshMaxSize   int
+	networkNeighbours    int
+	networkTopology      string
+	networkMTU           int
+	networkPodSelector   string
+	networkUDP           bool
+	networkUDPCount      int
+	networkZoneAware     bool
+	nodeName             string
+	ntpInterval          time.Duration
+	ntpKernelState       bool
+	ntpServers           string
+	podIPs               string
+	podName              string
+	port                 string
+	service              string
+	timeout              time.Duration
 )
 
 func init() {
+	flag.StringVar(&configFile, "config", "", "Path to a YAML or JSON probe configuration file, taking precedence over the other flags")
+	flag.DurationVar(&configReloadInterval, "config-reload-interval", 30*time.Second, "Interval between checks of the configuration file for changes")
 	flag.BoolVar(&disableDNSTCPCheck, "disable-dns-tcp-check", false, "Disable DNS TCP check")
 	flag.StringVar(&hosts, "hosts", "giantswarm.io.,kubernetes.default.svc.cluster.local.", "DNS hosts to resolve")
+	flag.DurationVar(&dnsInterval, "dns-interval", 30*time.Second, "Interval between DNS probes")
 	flag.StringVar(&dnsService, "dns-service", "coredns", "Name of DNS service")
 	flag.StringVar(&dnsNamespace, "dns-namespace", "kube-system", "Namespace of DNS service")
+	flag.StringVar(&dnsNodeLocalAddress, "dns-nodelocal-address", "", "IP of a node-local DNS cache to probe in addition to the DNS service, e.g. 169.254.20.10")
+	flag.IntVar(&dnsNodeLocalPort, "dns-nodelocal-port", 53, "Port of the node-local DNS cache")
+	flag.BoolVar(&dnsPerPod, "dns-per-pod", false, "Query each Pod of the DNS service directly instead of the service")
+	flag.StringVar(&dnsQueryTypes, "dns-query-types", dns.QueryTypeA, "DNS record types to query for each host, e.g. A,AAAA")
+	flag.DurationVar(&httpInterval, "http-interval", 30*time.Second, "Interval between HTTP probes")
+	flag.StringVar(&httpMethod, "http-method", http.MethodGet, "HTTP method to request URLs with, GET or HEAD")
+	flag.StringVar(&httpURLs, "http-urls", "", "Comma separated http or https URLs to request")
 	flag.StringVar(&namespace, "namespace", "monitoring", "Namespace of net-exporter service")
+	flag.BoolVar(&networkICMP, "network-icmp", false, "Ping the neighbours in addition to dialing them")
+	flag.IntVar(&networkICMPCount, "network-icmp-count", 5, "Number of ICMP echo requests sent to a host per round")
+	flag.DurationVar(&networkInterval, "network-interval", 30*time.Second, "Interval between network probes")
+	flag.IntVar(&networkMeshMaxSize, "network-mesh-max-size", 50, "Largest number of net-exporters for which the mesh topology dials all of them, falling back to the ring topology otherwise")
+	flag.IntVar(&networkNeighbours, "network-neighbours", network.DefaultNeighbours, "Number of neighbours to dial per round in the ring and rotating topologies")
+	flag.StringVar(&networkTopology, "network-topology", network.TopologyRing, "Selection of the neighbours to dial, ring, mesh or rotating")
+	flag.IntVar(&networkMTU, "network-mtu", 0, "MTU expected on the paths to the neighbours, probed with UDP echo requests with the Don't Fragment bit set, requires -network-udp, 0 disables it")
+	flag.StringVar(&networkPodSelector, "network-pod-selector", "app=net-exporter", "Label selector of the net-exporter pods, which are watched to ignore dial errors of deleted ones")
+	flag.BoolVar(&networkUDP, "network-udp", false, "Answer UDP echo requests on the port of the net-exporter service, and send them to the neighbours")
+	flag.IntVar(&networkUDPCount, "network-udp-count", 5, "Number of UDP echo requests sent to a host per round")
+	flag.BoolVar(&networkZoneAware, "network-zone-aware", false, "Also dial a neighbour in the own zone and one in each other zone, by the zones of the EndpointSlices and the topology labels of the nodes")
+	flag.StringVar(&nodeName, "node-name", "", "Name of the node of this net-exporter, for the source labels of network metrics, defaults to the node of its endpoint")
+	flag.DurationVar(&ntpInterval, "ntp-interval", 30*time.Second, "Interval between NTP probes")
+	flag.BoolVar(&ntpKernelState, "ntp-kernel-state", false, "Expose the synchronization state of the kernel clock of the node")
 	flag.StringVar(&ntpServers, "ntp-servers", "0.flatcar.pool.ntp.org,1.flatcar.pool.ntp.org", "NTP servers to use for time synchronization")
+	flag.StringVar(&podIPs, "pod-ips", "", "Comma separated IPs of the pod of this net-exporter, one per IP family, defaults to the global unicast IPs of its interfaces")
+	flag.StringVar(&podName, "pod-name", "", "Name of the pod of this net-exporter, for the source labels of network metrics, defaults to the pod of its endpoint")
 	flag.StringVar(&port, "port", "8000", "Port of net-exporter service")
 	flag.StringVar(&service, "service", "net-exporter", "Name of net-exporter service")
-	flag.DurationVar(&timeout, "timeout", 5*time.Second, "Timeout of the dialer")
+	flag.DurationVar(&timeout, "timeout", 5*time.Second, "Timeout of probes")
 }
 
 func main() {
@@ -56,8 +119,24 @@ func main() {
 
 	flag.Parse()
 
+	ctx := context.Background()
+
 	var err error
 
+	// The flags are a shorthand for the configuration file, and provide the
+	// defaults for anything the file does not set.
+	var probeConfig config.Config
+	{
+		probeConfig = flagConfig()
+
+		if configFile != "" {
+			probeConfig, err = config.Read(configFile, probeConfig)
+			if err != nil {
+				panic(microerror.JSON(err))
+			}
+		}
+	}
+
 	var logger micrologger.Logger
 	{
 		logger, err = micrologger.New(micrologger.Config{})
@@ -88,13 +167,53 @@ func main() {
 		}
 	}
 
-	var dnsCollector prometheus.Collector
+	// Every informer factory is scoped to the objects a collector looks up,
+	// so that net-exporters do not watch the whole namespaces of the DNS and
+	// net-exporter services. informerFactories holds all of them but the one
+	// of nodes, for starting them.
+	var informerFactories []informers.SharedInformerFactory
+	var dnsEndpointSliceInformerFactory, dnsServiceInformerFactory informers.SharedInformerFactory
+	var networkEndpointSliceInformerFactory, networkPodInformerFactory, networkServiceInformerFactory informers.SharedInformerFactory
+	var nodeInformerFactory metadatainformer.SharedInformerFactory
 	{
-		splitHosts := strings.Split(hosts, ",")
+		podSelector, err := labels.Parse(probeConfig.Network.PodSelector)
+		if err != nil {
+			panic(microerror.JSON(err))
+		}
 
+		dnsEndpointSliceInformerFactory = newInformerFactory(k8sClient, probeConfig.DNS.Namespace, endpointSlicesOf(probeConfig.DNS.Service))
+		dnsServiceInformerFactory = newInformerFactory(k8sClient, probeConfig.DNS.Namespace, named(probeConfig.DNS.Service))
+		networkEndpointSliceInformerFactory = newInformerFactory(k8sClient, probeConfig.Network.Namespace, endpointSlicesOf(probeConfig.Network.Service))
+		networkPodInformerFactory = newInformerFactory(k8sClient, probeConfig.Network.Namespace, labeled(podSelector))
+		networkServiceInformerFactory = newInformerFactory(k8sClient, probeConfig.Network.Namespace, named(probeConfig.Network.Service))
+
+		informerFactories = append(informerFactories,
+			dnsEndpointSliceInformerFactory,
+			dnsServiceInformerFactory,
+			networkEndpointSliceInformerFactory,
+			networkPodInformerFactory,
+			networkServiceInformerFactory,
+		)
+
+		// Nodes are not namespaced, and only their topology labels are looked
+		// up by the zone aware network collector, so only their metadata is
+		// watched.
+		if probeConfig.Network.ZoneAware {
+			metadataClient, err := metadata.NewForConfig(restConfig)
+			if err != nil {
+				panic(microerror.JSON(err))
+			}
+
+			nodeInformerFactory = metadatainformer.NewSharedInformerFactory(metadataClient, 0)
+		}
+	}
+
+	var dnsCollector *dns.Collector
+	{
 		c := dns.Config{
-			K8sClient: k8sClient,
-			Logger:    logger,
+			EndpointSliceInformerFactory: dnsEndpointSliceInformerFactory,
+			ServiceInformerFactory:       dnsServiceInformerFactory,
+			Logger:                       logger,
 			TCPClient: &dnsclient.Client{
 				Net: "tcp",
 			},
@@ -102,46 +221,79 @@ func main() {
 				Net: "udp",
 			},
 
-			DisableTCPCheck: disableDNSTCPCheck,
-			Hosts:           splitHosts,
-			Service:         dnsService,
-			Namespace:       dnsNamespace,
+			Service:          probeConfig.DNS.Service,
+			Namespace:        probeConfig.DNS.Namespace,
+			PerPod:           probeConfig.DNS.PerPod,
+			NodeLocalAddress: probeConfig.DNSNodeLocalAddress(),
+			Targets:          probeConfig.DNSTargets(),
 		}
 
 		dnsCollector, err = dns.New(c)
 		if err != nil {
-			panic(fmt.Sprintf("%#v\n", err))
+			panic(microerror.JSON(err))
 		}
 	}
 
-	var networkCollector prometheus.Collector
+	var networkCollector *network.Collector
 	{
 		c := network.Config{
 			Dialer: &net.Dialer{
-				Timeout: timeout,
+				Timeout: probeConfig.Network.Timeout.Duration,
 			},
-			K8sClient: k8sClient,
-			Logger:    logger,
+			EndpointSliceInformerFactory: networkEndpointSliceInformerFactory,
+			PodInformerFactory:           networkPodInformerFactory,
+			ServiceInformerFactory:       networkServiceInformerFactory,
+			Logger:                       logger,
+			NodeInformerFactory:          nodeInformerFactory,
+
+			Interval:  probeConfig.Network.Interval.Duration,
+			Namespace: probeConfig.Network.Namespace,
+			NodeName:  nodeName,
+			PodName:   podName,
+			PodIPs:    splitPodIPs(podIPs),
+			Port:      probeConfig.Network.Port,
+			Service:   probeConfig.Network.Service,
+			Targets:   probeConfig.NetworkTargets(),
 
-			Namespace: namespace,
-			Port:      port,
-			Service:   service,
+			Topology:    probeConfig.Network.Topology,
+			Neighbours:  probeConfig.Network.Neighbours,
+			MeshMaxSize: probeConfig.Network.MeshMaxSize,
+			ZoneAware:   probeConfig.Network.ZoneAware,
+
+			ICMP:      probeConfig.Network.ICMP,
+			ICMPCount: probeConfig.Network.ICMPCount,
+			UDP:       probeConfig.Network.UDP,
+			UDPCount:  probeConfig.Network.UDPCount,
+			MTU:       probeConfig.Network.MTU,
 		}
 
 		networkCollector, err = network.New(c)
 		if err != nil {
-			panic(fmt.Sprintf("%#v\n", err))
+			panic(microerror.JSON(err))
 		}
 	}
 
-	var ntpCollector prometheus.Collector
+	var httpCollector *http.Collector
 	{
-		splitNTPServers := strings.Split(ntpServers, ",")
+		c := http.Config{
+			Logger: logger,
+
+			Targets: probeConfig.HTTPTargets(),
+		}
 
+		httpCollector, err = http.New(c)
+		if err != nil {
+			panic(microerror.JSON(err))
+		}
+	}
+
+	var ntpCollector *ntp.Collector
+	{
 		c := ntp.Config{
 			Logger: logger,
 
-			NTPServers: splitNTPServers,
+			KernelState: probeConfig.NTP.KernelState,
+			Targets:     probeConfig.NTPTargets(),
 		}
 
 		ntpCollector, err = ntp.New(c)
@@ -150,14 +302,72 @@ func main() {
 		}
 	}
 
+	for _, informerFactory := range informerFactories {
+		informerFactory.Start(ctx.Done())
+
+		for informerType, synced := range informerFactory.WaitForCacheSync(ctx.Done()) {
+			if !synced {
+				panic(fmt.Sprintf("failed to sync informer cache for %v", informerType))
+			}
+		}
+	}
+	if nodeInformerFactory != nil {
+		nodeInformerFactory.Start(ctx.Done())
+
+		for resource, synced := range nodeInformerFactory.WaitForCacheSync(ctx.Done()) {
+			if !synced {
+				panic(fmt.Sprintf("failed to sync informer cache for %v", resource))
+			}
+		}
+	}
+
+	var probeScheduler *scheduler.Scheduler
+	{
+		c := scheduler.Config{
+			Logger: logger,
+
+			Probers: []scheduler.Prober{
+				dnsCollector,
+				httpCollector,
+				networkCollector,
+				ntpCollector,
+			},
+		}
+
+		probeScheduler, err = scheduler.New(c)
+		if err != nil {
+			panic(fmt.Sprintf("%#v\n", err))
+		}
+	}
+
 	var extraEndpoints []server.Endpoint
+	for _, path := range []string{endpoints.BlackboxPath, endpoints.BlackboxLegacyPath} {
+		c := endpoints.BlackboxConfig{
+			DNSCollector: dnsCollector,
+			Logger:       logger,
+
+			Path:    path,
+			Timeout: timeout,
+		}
+
+		blackboxEndpoint, err := endpoints.NewBlackbox(c)
+		if err != nil {
+			panic(fmt.Sprintf("%#v\n", err))
+		}
+
+		extraEndpoints = append(extraEndpoints, blackboxEndpoint)
+	}
 	{
-		blackboxEndpoint, err := endpoints.NewBlackbox(endpoints.BlackboxConfig{})
+		c := endpoints.EchoConfig{
+			Logger: logger,
+		}
+
+		echoEndpoint, err := endpoints.NewEcho(c)
 		if err != nil {
 			panic(fmt.Sprintf("%#v\n", err))
 		}
 
-		extraEndpoints = []server.Endpoint{blackboxEndpoint}
+		extraEndpoints = append(extraEndpoints, echoEndpoint)
 	}
 
 	var exporter *exporterkit.Exporter
@@ -165,8 +375,10 @@ func main() {
 		c := exporterkit.Config{
 			Collectors: []prometheus.Collector{
 				dnsCollector,
+				httpCollector,
 				networkCollector,
 				ntpCollector,
+				probeScheduler,
 			},
 			ExtraEndpoints: extraEndpoints,
 			Logger:         logger,
@@ -178,5 +390,201 @@ func main() {
 		}
 	}
 
+	if configFile != "" {
+		c := config.WatcherConfig{
+			Logger: logger,
+			Reload: func(c config.Config) error {
+				return reloadProbes(c, dnsCollector, httpCollector, networkCollector, ntpCollector, probeScheduler)
+			},
+
+			Current:  probeConfig,
+			Defaults: flagConfig(),
+			Interval: configReloadInterval,
+			Path:     configFile,
+		}
+
+		configWatcher, err := config.NewWatcher(c)
+		if err != nil {
+			panic(fmt.Sprintf("%#v\n", err))
+		}
+
+		go configWatcher.Run(ctx)
+	}
+
+	if probeConfig.Network.UDP {
+		c := network.UDPResponderConfig{
+			Logger: logger,
+
+			Address: net.JoinHostPort("", probeConfig.Network.Port),
+		}
+
+		udpResponder, err := network.NewUDPResponder(c)
+		if err != nil {
+			panic(fmt.Sprintf("%#v\n", err))
+		}
+
+		go udpResponder.Run(ctx)
+	}
+
+	go probeScheduler.Run(ctx)
+
 	exporter.Run()
 }
+
+// reloadProbes reconfigures the collectors in place with the targets of the
+// given configuration, and syncs the scheduler with their new jobs. All
+// targets are validated upfront, so that the configuration is either applied
+// entirely or not at all.
+func reloadProbes(c config.Config, dnsCollector *dns.Collector, httpCollector *http.Collector, networkCollector *network.Collector, ntpCollector *ntp.Collector, probeScheduler *scheduler.Scheduler) error {
+	dnsTargets := c.DNSTargets()
+	httpTargets := c.HTTPTargets()
+	networkTargets := c.NetworkTargets()
+	ntpTargets := c.NTPTargets()
+
+	err := dns.ValidateTargets(dnsTargets)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	err = http.ValidateTargets(httpTargets)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	err = network.ValidateTargets(networkTargets)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	err = ntp.ValidateTargets(ntpTargets)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = dnsCollector.Reconfigure(dnsTargets)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	err = httpCollector.Reconfigure(httpTargets)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	err = networkCollector.Reconfigure(networkTargets)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	err = ntpCollector.Reconfigure(ntpTargets)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = probeScheduler.Sync()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// flagConfig returns the probe configuration equivalent to the flags.
+func flagConfig() config.Config {
+	c := config.Config{
+		DNS: config.DNS{
+			Namespace: dnsNamespace,
+			Service:   dnsService,
+			PerPod:    dnsPerPod,
+			NodeLocal: config.DNSNodeLocal{
+				Address: dnsNodeLocalAddress,
+				Port:    dnsNodeLocalPort,
+			},
+			Interval:  metav1.Duration{Duration: dnsInterval},
+			Timeout:   metav1.Duration{Duration: timeout},
+			Protocols: []string{dns.ProtocolUDP, dns.ProtocolTCP},
+		},
+		HTTP: config.HTTP{
+			Interval: metav1.Duration{Duration: httpInterval},
+			Timeout:  metav1.Duration{Duration: timeout},
+			Method:   httpMethod,
+		},
+		Network: config.Network{
+			Namespace:   namespace,
+			Service:     service,
+			Port:        port,
+			Interval:    metav1.Duration{Duration: networkInterval},
+			Timeout:     metav1.Duration{Duration: timeout},
+			Topology:    networkTopology,
+			Neighbours:  networkNeighbours,
+			MeshMaxSize: networkMeshMaxSize,
+			PodSelector: networkPodSelector,
+			ZoneAware:   networkZoneAware,
+			ICMP:        networkICMP,
+			ICMPCount:   networkICMPCount,
+			UDP:         networkUDP,
+			UDPCount:    networkUDPCount,
+			MTU:         networkMTU,
+		},
+		NTP: config.NTP{
+			KernelState: ntpKernelState,
+			Interval:    metav1.Duration{Duration: ntpInterval},
+			Timeout:     metav1.Duration{Duration: timeout},
+		},
+	}
+
+	if disableDNSTCPCheck {
+		c.DNS.Protocols = []string{dns.ProtocolUDP}
+	}
+
+	c.DNS.QueryTypes = strings.Split(dnsQueryTypes, ",")
+
+	for _, host := range strings.Split(hosts, ",") {
+		c.DNS.Targets = append(c.DNS.Targets, config.DNSTarget{Host: host})
+	}
+
+	if httpURLs != "" {
+		for _, u := range strings.Split(httpURLs, ",") {
+			c.HTTP.Targets = append(c.HTTP.Targets, config.HTTPTarget{URL: u})
+		}
+	}
+
+	for _, server := range strings.Split(ntpServers, ",") {
+		c.NTP.Targets = append(c.NTP.Targets, config.NTPTarget{Server: server})
+	}
+
+	return c
+}
+
+// splitPodIPs splits the comma separated pod IPs, which are empty if the
+// downward API does not provide them.
+func splitPodIPs(podIPs string) []string {
+	var ips []string
+	for _, ip := range strings.Split(podIPs, ",") {
+		if ip != "" {
+			ips = append(ips, ip)
+		}
+	}
+
+	return ips
+}
+
+// newInformerFactory returns an informer factory for the given namespace,
+// whose informers only list and watch the objects selected by tweak.
+func newInformerFactory(k8sClient kubernetes.Interface, namespace string, tweak func(*metav1.ListOptions)) informers.SharedInformerFactory {
+	return informers.NewSharedInformerFactoryWithOptions(k8sClient, 0, informers.WithNamespace(namespace), informers.WithTweakListOptions(tweak))
+}
+
+// named selects the object with the given name.
+func named(name string) func(*metav1.ListOptions) {
+	return func(options *metav1.ListOptions) {
+		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+	}
+}
+
+// endpointSlicesOf selects the EndpointSlices of the service with the given
+// name.
+func endpointSlicesOf(service string) func(*metav1.ListOptions) {
+	return labeled(labels.SelectorFromSet(labels.Set{discoveryv1.LabelServiceName: service}))
+}
+
+// labeled selects the objects matching the given label selector.
+func labeled(selector labels.Selector) func(*metav1.ListOptions) {
+	return func(options *metav1.ListOptions) {
+		options.LabelSelector = selector.String()
+	}
+}
@@ -0,0 +1,110 @@
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// EchoPath is the HTTP path on which net-exporters answer echo requests.
+	EchoPath = "/echo"
+	// MaxEchoPayload is the largest payload of echo requests answered.
+	MaxEchoPayload = 1024
+
+	// echoPayloadSize is the size of the random payload of echo requests.
+	echoPayloadSize = 64
+
+	echoReasonConnection = "connection"
+	echoReasonMismatch   = "mismatch"
+)
+
+// echoMagic starts every echo response, so that it is not mistaken for the
+// answer of another HTTP server which echoes request bodies.
+var echoMagic = []byte("net-exporter/echo/1\n")
+
+// EchoResponse returns the body of the response to an echo request with the
+// given payload.
+func EchoResponse(payload []byte) []byte {
+	return append(append([]byte{}, echoMagic...), payload...)
+}
+
+// echo sends an echo request with a random payload on the given connection to
+// the net-exporter on host, and returns the time until its response was read.
+// It returns an echoMismatchError if the response is not the one of a
+// net-exporter, and other errors if the connection broke, e.g. because it was
+// half-open.
+func echo(ctx context.Context, logger micrologger.Logger, conn net.Conn, host string, timeout time.Duration) (time.Duration, error) {
+	payload := make([]byte, echoPayloadSize)
+	_, err := rand.Read(payload)
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+host+EchoPath, bytes.NewReader(payload))
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+	req.Close = true
+
+	err = conn.SetDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+
+	start := time.Now()
+
+	err = req.Write(conn)
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Log("level", "error", "message", fmt.Sprintf("failed to close echo response body for host %#q", host), "stack", microerror.JSON(err))
+		}
+	}()
+
+	expected := EchoResponse(payload)
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(len(expected)+1)))
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+
+	elapsed := time.Since(start)
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, microerror.Maskf(echoMismatchError, "response status %d", resp.StatusCode)
+	}
+	if !bytes.Equal(body, expected) {
+		return 0, microerror.Maskf(echoMismatchError, "response body does not echo the payload")
+	}
+
+	return elapsed, nil
+}
+
+// newEchoHistogramDesc returns the descriptor of the echo round trip time
+// histograms.
+func newEchoHistogramDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "echo", "rtt_seconds"),
+		"Histogram of round trip times of echo requests on dialed connections.",
+		withPeerLabels("host"),
+		nil,
+	)
+}
@@ -0,0 +1,144 @@
+package network
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/client-go/tools/cache"
+)
+
+func Test_echo(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(EchoPath, func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(EchoResponse(payload))
+	})
+	netExporter := httptest.NewServer(mux)
+	defer netExporter.Close()
+
+	// Another HTTP server, which echoes request bodies too.
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(w, r.Body)
+	}))
+	defer other.Close()
+
+	// A listener which accepts connections, but never answers.
+	silent, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	defer silent.Close() // nolint: errcheck
+
+	testCases := []struct {
+		name         string
+		inputHost    string
+		errorMatcher func(error) bool
+	}{
+		{
+			name:         "case 0: a net-exporter echoes the payload",
+			inputHost:    netExporter.Listener.Addr().String(),
+			errorMatcher: nil,
+		},
+		{
+			name:         "case 1: other echo servers do not match",
+			inputHost:    other.Listener.Addr().String(),
+			errorMatcher: IsEchoMismatch,
+		},
+		{
+			name:      "case 2: silent connections time out",
+			inputHost: silent.Addr().String(),
+			errorMatcher: func(err error) bool {
+				return err != nil && !IsEchoMismatch(err)
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			conn, err := net.Dial("tcp", tc.inputHost)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			defer conn.Close() // nolint: errcheck
+
+			elapsed, err := echo(context.Background(), microloggertest.New(), conn, tc.inputHost, 500*time.Millisecond)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher == nil && elapsed <= 0 {
+				t.Fatalf("elapsed == %v, want positive", elapsed)
+			}
+		})
+	}
+}
+
+func Test_Collector_echo(t *testing.T) {
+	// An HTTP server which echoes request bodies, but is no net-exporter.
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(w, r.Body)
+	}))
+	defer other.Close()
+
+	testCases := []struct {
+		name          string
+		inputIsPeer   bool
+		expectedCount int
+	}{
+		{
+			name:          "case 0: errors of service hosts are counted",
+			inputIsPeer:   false,
+			expectedCount: 1,
+		},
+		{
+			name:          "case 1: errors of neighbours without pod are ignored",
+			inputIsPeer:   true,
+			expectedCount: 0,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			c := &Collector{
+				logger:     microloggertest.New(),
+				podIndexer: cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{podIPIndex: podIPIndexFunc}),
+				echoErrorCount: prometheus.NewCounterVec(
+					prometheus.CounterOpts{Name: "echo_error_total"},
+					withPeerLabels("host", "reason"),
+				),
+			}
+
+			host := other.Listener.Addr().String()
+
+			conn, err := net.Dial("tcp", host)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			defer conn.Close() // nolint: errcheck
+
+			c.echo(context.Background(), conn, host, 500*time.Millisecond, tc.inputIsPeer)
+
+			count := testutil.CollectAndCount(c.echoErrorCount)
+			if count != tc.expectedCount {
+				t.Fatalf("count == %v, want %v", count, tc.expectedCount)
+			}
+		})
+	}
+}
@@ -12,3 +12,39 @@ var invalidConfigError = &microerror.Error{
 func IsInvalidConfig(err error) bool {
 	return microerror.Cause(err) == invalidConfigError
 }
+
+var wrongTypeError = &microerror.Error{
+	Kind: "wrongTypeError",
+}
+
+// IsWrongType asserts wrongTypeError.
+func IsWrongType(err error) bool {
+	return microerror.Cause(err) == wrongTypeError
+}
+
+var tlsHandshakeFailedError = &microerror.Error{
+	Kind: "tlsHandshakeFailedError",
+}
+
+// IsTLSHandshakeFailed asserts tlsHandshakeFailedError.
+func IsTLSHandshakeFailed(err error) bool {
+	return microerror.Cause(err) == tlsHandshakeFailedError
+}
+
+var echoMismatchError = &microerror.Error{
+	Kind: "echoMismatchError",
+}
+
+// IsEchoMismatch asserts echoMismatchError.
+func IsEchoMismatch(err error) bool {
+	return microerror.Cause(err) == echoMismatchError
+}
+
+var ipNotFoundError = &microerror.Error{
+	Kind: "ipNotFoundError",
+}
+
+// IsIPNotFound asserts ipNotFoundError.
+func IsIPNotFound(err error) bool {
+	return microerror.Cause(err) == ipNotFoundError
+}
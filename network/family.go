@@ -0,0 +1,62 @@
+package network
+
+import (
+	"net"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	ipFamilyIPv4 = "ipv4"
+	ipFamilyIPv6 = "ipv6"
+)
+
+// ipFamily returns the IP family of the given host, which is an IP, or in
+// host:port form. It is empty for hosts which are not IPs, e.g. the DNS names
+// of Targets.
+func ipFamily(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return ""
+	case ip.To4() != nil:
+		return ipFamilyIPv4
+	default:
+		return ipFamilyIPv6
+	}
+}
+
+// serviceIPs returns the cluster IPs of the given service, one per IP family.
+func serviceIPs(service *corev1.Service) []string {
+	if len(service.Spec.ClusterIPs) > 0 {
+		return service.Spec.ClusterIPs
+	}
+
+	return []string{service.Spec.ClusterIP}
+}
+
+// withOtherFamilies returns the given neighbours, followed by the addresses of
+// the other IP families of the same net-exporter pods, so that dual-stack
+// net-exporters probe the same neighbours over both families. The given IP,
+// which small rings wrap around to, is not expanded.
+func withOtherFamilies(ip string, neighbours []string, addresses []string, peers map[string]peer) []string {
+	expanded := append([]string{}, neighbours...)
+	for _, neighbour := range neighbours {
+		p := peers[neighbour]
+		if neighbour == ip || p.pod == "" {
+			continue
+		}
+
+		for _, address := range addresses {
+			if peers[address].pod == p.pod && ipFamily(address) != ipFamily(neighbour) {
+				expanded = append(expanded, address)
+			}
+		}
+	}
+
+	return expanded
+}
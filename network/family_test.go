@@ -0,0 +1,94 @@
+package network
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_ipFamily(t *testing.T) {
+	testCases := []struct {
+		name             string
+		inputHost        string
+		expectedIPFamily string
+	}{
+		{
+			name:             "case 0: IPv4 host:port",
+			inputHost:        "10.0.0.1:8000",
+			expectedIPFamily: ipFamilyIPv4,
+		},
+		{
+			name:             "case 1: IPv6 host:port",
+			inputHost:        "[fd00::1]:8000",
+			expectedIPFamily: ipFamilyIPv6,
+		},
+		{
+			name:             "case 2: IPv6 IP",
+			inputHost:        "fd00::1",
+			expectedIPFamily: ipFamilyIPv6,
+		},
+		{
+			name:             "case 3: DNS names have no IP family",
+			inputHost:        "kubernetes.default.svc:443",
+			expectedIPFamily: "",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			family := ipFamily(tc.inputHost)
+
+			if family != tc.expectedIPFamily {
+				t.Fatalf("ipFamily == %#q, want %#q", family, tc.expectedIPFamily)
+			}
+		})
+	}
+}
+
+func Test_withOtherFamilies(t *testing.T) {
+	addresses := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "fd00::1", "fd00::2"}
+	peers := map[string]peer{
+		"10.0.0.1": {pod: "net-exporter-a"},
+		"10.0.0.2": {pod: "net-exporter-b"},
+		"10.0.0.3": {pod: "net-exporter-c"},
+		"fd00::1":  {pod: "net-exporter-a"},
+		"fd00::2":  {pod: "net-exporter-b"},
+	}
+
+	testCases := []struct {
+		name               string
+		inputIP            string
+		inputNeighbours    []string
+		expectedNeighbours []string
+	}{
+		{
+			name:               "case 0: the IPv6 addresses of IPv4 neighbours are added",
+			inputIP:            "10.0.0.3",
+			inputNeighbours:    []string{"10.0.0.1", "10.0.0.2"},
+			expectedNeighbours: []string{"10.0.0.1", "10.0.0.2", "fd00::1", "fd00::2"},
+		},
+		{
+			name:               "case 1: the own IP is not expanded",
+			inputIP:            "10.0.0.1",
+			inputNeighbours:    []string{"10.0.0.3", "10.0.0.1"},
+			expectedNeighbours: []string{"10.0.0.3", "10.0.0.1"},
+		},
+		{
+			name:               "case 2: the IPv4 addresses of IPv6 neighbours are added",
+			inputIP:            "fd00::1",
+			inputNeighbours:    []string{"fd00::2"},
+			expectedNeighbours: []string{"fd00::2", "10.0.0.2"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			neighbours := withOtherFamilies(tc.inputIP, tc.inputNeighbours, addresses, peers)
+
+			if !cmp.Equal(neighbours, tc.expectedNeighbours) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedNeighbours, neighbours))
+			}
+		})
+	}
+}
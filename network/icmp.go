@@ -0,0 +1,297 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"os"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	protocolICMP   = 1
+	protocolICMPv6 = 58
+)
+
+// rttStatistics are the round trip time statistics exposed for each host.
+var rttStatistics = []string{"min", "avg", "max"}
+
+// pingPayload is sent in every echo request, so that replies are easy to
+// recognize in packet captures.
+var pingPayload = []byte("net-exporter icmp echo probe")
+
+// pingResult is the outcome of a round of ICMP echo requests to a host.
+type pingResult struct {
+	sent     int
+	received int
+	minRTT   time.Duration
+	avgRTT   time.Duration
+	maxRTT   time.Duration
+	// jitter is the mean absolute difference between the round trip times of
+	// consecutive replies.
+	jitter time.Duration
+}
+
+// lossRatio returns the ratio of echo requests which were not answered.
+func (r pingResult) lossRatio() float64 {
+	if r.sent == 0 {
+		return 0
+	}
+
+	return float64(r.sent-r.received) / float64(r.sent)
+}
+
+// newPingResult summarizes the round trip times of the replies to sent echo
+// requests, in the order they were sent.
+func newPingResult(sent int, rtts []time.Duration) pingResult {
+	r := pingResult{
+		sent:     sent,
+		received: len(rtts),
+	}
+	if len(rtts) == 0 {
+		return r
+	}
+
+	var sum, deltas time.Duration
+	r.minRTT = rtts[0]
+	for i, rtt := range rtts {
+		sum += rtt
+		r.minRTT = min(r.minRTT, rtt)
+		r.maxRTT = max(r.maxRTT, rtt)
+
+		if i > 0 {
+			delta := rtt - rtts[i-1]
+			if delta < 0 {
+				delta = -delta
+			}
+			deltas += delta
+		}
+	}
+
+	r.avgRTT = sum / time.Duration(len(rtts))
+	if len(rtts) > 1 {
+		r.jitter = deltas / time.Duration(len(rtts)-1)
+	}
+
+	return r
+}
+
+// pinger sends ICMP echo requests to a single IP.
+type pinger struct {
+	conn *icmp.PacketConn
+	dst  net.Addr
+	ip   net.IP
+	// privileged is set for raw sockets, which receive the replies to every
+	// socket of the host, instead of datagram sockets, for which the kernel
+	// sets the identifier and only delivers the replies to the socket.
+	privileged bool
+	id         int
+	protocol   int
+	echoType   icmp.Type
+	replyType  icmp.Type
+}
+
+// ping sends count ICMP echo requests to the given host, one after the other,
+// waiting up to timeout divided by count for each reply. Unprivileged
+// datagram sockets are used where the ping_group_range sysctl permits, raw
+// sockets otherwise.
+func ping(ctx context.Context, logger micrologger.Logger, host string, count int, timeout time.Duration) (pingResult, error) {
+	ip, err := resolveIP(ctx, host)
+	if err != nil {
+		return pingResult{}, microerror.Mask(err)
+	}
+
+	p, err := newPinger(ip)
+	if err != nil {
+		return pingResult{}, microerror.Mask(err)
+	}
+	defer func() {
+		if err := p.conn.Close(); err != nil {
+			logger.Log("level", "error", "message", fmt.Sprintf("failed to close ICMP connection for host %#q", host), "stack", microerror.JSON(err))
+		}
+	}()
+
+	packetTimeout := timeout / time.Duration(count)
+
+	var rtts []time.Duration
+	for seq := range count {
+		if ctx.Err() != nil {
+			return pingResult{}, microerror.Mask(ctx.Err())
+		}
+
+		deadline := time.Now().Add(packetTimeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+
+		rtt, ok, err := p.echo(seq, deadline)
+		if err != nil {
+			return pingResult{}, microerror.Mask(err)
+		}
+		if ok {
+			rtts = append(rtts, rtt)
+		}
+	}
+
+	return newPingResult(count, rtts), nil
+}
+
+// resolveIP returns the IP of the given host, preferring IPv4.
+func resolveIP(ctx context.Context, host string) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip, nil
+	}
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip, nil
+		}
+	}
+
+	return ips[0], nil
+}
+
+func newPinger(ip net.IP) (*pinger, error) {
+	p := &pinger{
+		ip: ip,
+		// The identifier only matters for raw sockets.
+		id: rand.IntN(1 << 16), // nolint: gosec
+	}
+
+	network, address, rawNetwork := "udp4", "0.0.0.0", "ip4:icmp"
+	p.protocol, p.echoType, p.replyType = protocolICMP, ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	if ip.To4() == nil {
+		network, address, rawNetwork = "udp6", "::", "ip6:ipv6-icmp"
+		p.protocol, p.echoType, p.replyType = protocolICMPv6, ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+
+	conn, err := icmp.ListenPacket(network, address)
+	if err == nil {
+		p.conn = conn
+		p.dst = &net.UDPAddr{IP: ip}
+		return p, nil
+	}
+
+	conn, rawErr := icmp.ListenPacket(rawNetwork, address)
+	if rawErr != nil {
+		return nil, microerror.Mask(errors.Join(err, rawErr))
+	}
+	p.conn = conn
+	p.dst = &net.IPAddr{IP: ip}
+	p.privileged = true
+
+	return p, nil
+}
+
+// echo sends a single echo request with the given sequence number, and waits
+// for its reply until deadline. It returns false if no reply arrived in time.
+func (p *pinger) echo(seq int, deadline time.Time) (time.Duration, bool, error) {
+	msg := icmp.Message{
+		Type: p.echoType,
+		Body: &icmp.Echo{
+			ID:   p.id,
+			Seq:  seq,
+			Data: pingPayload,
+		},
+	}
+	// The kernel computes the checksum of ICMPv6 messages.
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return 0, false, microerror.Mask(err)
+	}
+
+	err = p.conn.SetReadDeadline(deadline)
+	if err != nil {
+		return 0, false, microerror.Mask(err)
+	}
+
+	start := time.Now()
+
+	_, err = p.conn.WriteTo(b, p.dst)
+	if err != nil {
+		return 0, false, microerror.Mask(err)
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := p.conn.ReadFrom(buf)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return 0, false, nil
+		} else if err != nil {
+			return 0, false, microerror.Mask(err)
+		}
+		elapsed := time.Since(start)
+
+		if !p.ip.Equal(addrIP(peer)) {
+			continue
+		}
+
+		reply, err := icmp.ParseMessage(p.protocol, buf[:n])
+		if err != nil || reply.Type != p.replyType {
+			continue
+		}
+		e, ok := reply.Body.(*icmp.Echo)
+		if !ok || e.Seq != seq || (p.privileged && e.ID != p.id) {
+			continue
+		}
+
+		return elapsed, true, nil
+	}
+}
+
+func addrIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP
+	case *net.IPAddr:
+		return a.IP
+	}
+
+	return nil
+}
+
+// newICMPLossDesc returns the descriptor of the ICMP packet loss gauge, with
+// the given constant labels.
+func newICMPLossDesc(constLabels map[string]string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "icmp", "packet_loss_ratio"),
+		"Ratio of ICMP echo requests without reply in the last round.",
+		withPeerLabels("host"),
+		constLabels,
+	)
+}
+
+// newICMPRTTDesc returns the descriptor of the given ICMP round trip time
+// statistic of the last round, e.g. min, with the given constant labels.
+func newICMPRTTDesc(statistic string, constLabels map[string]string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "icmp", "rtt_"+statistic+"_seconds"),
+		"The "+statistic+" round trip time of ICMP echo requests in the last round.",
+		withPeerLabels("host"),
+		constLabels,
+	)
+}
+
+// newICMPJitterDesc returns the descriptor of the ICMP jitter gauge, with the
+// given constant labels.
+func newICMPJitterDesc(constLabels map[string]string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "icmp", "jitter_seconds"),
+		"Mean difference between the round trip times of consecutive ICMP echo replies in the last round.",
+		withPeerLabels("host"),
+		constLabels,
+	)
+}
@@ -0,0 +1,91 @@
+package network
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_newPingResult(t *testing.T) {
+	testCases := []struct {
+		name              string
+		inputSent         int
+		inputRTTs         []time.Duration
+		expectedResult    pingResult
+		expectedLossRatio float64
+	}{
+		{
+			name:      "case 0: no replies",
+			inputSent: 3,
+			inputRTTs: nil,
+			expectedResult: pingResult{
+				sent: 3,
+			},
+			expectedLossRatio: 1,
+		},
+		{
+			name:      "case 1: a single reply has no jitter",
+			inputSent: 2,
+			inputRTTs: []time.Duration{4 * time.Millisecond},
+			expectedResult: pingResult{
+				sent:     2,
+				received: 1,
+				minRTT:   4 * time.Millisecond,
+				avgRTT:   4 * time.Millisecond,
+				maxRTT:   4 * time.Millisecond,
+			},
+			expectedLossRatio: 0.5,
+		},
+		{
+			name:      "case 2: jitter is the mean difference of consecutive replies",
+			inputSent: 4,
+			inputRTTs: []time.Duration{2 * time.Millisecond, 6 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond},
+			expectedResult: pingResult{
+				sent:     4,
+				received: 4,
+				minRTT:   2 * time.Millisecond,
+				avgRTT:   4 * time.Millisecond,
+				maxRTT:   6 * time.Millisecond,
+				jitter:   2 * time.Millisecond,
+			},
+			expectedLossRatio: 0,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			r := newPingResult(tc.inputSent, tc.inputRTTs)
+
+			if !cmp.Equal(r, tc.expectedResult, cmp.AllowUnexported(pingResult{})) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedResult, r, cmp.AllowUnexported(pingResult{})))
+			}
+			if r.lossRatio() != tc.expectedLossRatio {
+				t.Fatalf("lossRatio == %v, want %v", r.lossRatio(), tc.expectedLossRatio)
+			}
+		})
+	}
+}
+
+func Test_ping(t *testing.T) {
+	p, err := newPinger([]byte{127, 0, 0, 1})
+	if err != nil {
+		t.Skipf("ICMP sockets are not permitted: %s", err)
+	}
+	_ = p.conn.Close()
+
+	r, err := ping(context.Background(), microloggertest.New(), "127.0.0.1", 3, 3*time.Second)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	if r.sent != 3 || r.received != 3 {
+		t.Fatalf("received %d of %d replies, want 3 of 3", r.received, r.sent)
+	}
+	if r.minRTT <= 0 || r.minRTT > r.avgRTT || r.avgRTT > r.maxRTT {
+		t.Fatalf("min, avg and max round trip times == %v, %v, %v, want ascending and positive", r.minRTT, r.avgRTT, r.maxRTT)
+	}
+}
@@ -0,0 +1,146 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+	"net"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// mtuAttempts is the number of UDP echo requests of a size sent before
+	// the size is considered not to pass, so that a single lost packet does
+	// not lower the result.
+	mtuAttempts = 2
+
+	ipv4HeaderSize = 20
+	ipv6HeaderSize = 40
+	udpHeaderSize  = 8
+
+	// maxIPPacketSize is the largest size of IPv4 packets, and of IPv6
+	// packets without jumbograms.
+	maxIPPacketSize = 65535
+)
+
+// probeMTU returns the largest payload of UDP echo requests with the Don't
+// Fragment bit set which the UDPResponder on the given host answers, from the
+// minimum size of UDP echo requests up to the MTU of the route to the host.
+// It returns 0 if not even the smallest request is answered. The timeout is
+// split evenly over all requests the search may need.
+func probeMTU(ctx context.Context, logger micrologger.Logger, dialer *net.Dialer, host string, timeout time.Duration) (int, error) {
+	d := *dialer
+	d.Control = dontFragment
+
+	conn, err := d.DialContext(ctx, "udp", host)
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			logger.Log("level", "error", "message", fmt.Sprintf("failed to close MTU probe connection for host %#q", host), "stack", microerror.JSON(err))
+		}
+	}()
+
+	routeMTU, err := readRouteMTU(conn.(*net.UDPConn))
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+
+	headerSize := ipv4HeaderSize + udpHeaderSize
+	if conn.RemoteAddr().(*net.UDPAddr).IP.To4() == nil {
+		headerSize = ipv6HeaderSize + udpHeaderSize
+	}
+
+	lo := udpEchoSize
+	hi := max(min(routeMTU, maxIPPacketSize)-headerSize, lo)
+
+	// The largest size and the smallest one are tried first, then the
+	// largest passing size between them is searched for.
+	steps := 2 + bits.Len(uint(hi-lo)) // nolint: gosec
+	packetTimeout := timeout / time.Duration(steps*mtuAttempts)
+
+	var seq uint32
+	passes := func(size int) (bool, error) {
+		for range mtuAttempts {
+			if ctx.Err() != nil {
+				return false, microerror.Mask(ctx.Err())
+			}
+
+			deadline := time.Now().Add(packetTimeout)
+			if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+				deadline = d
+			}
+
+			seq++
+			_, ok, err := udpEchoOnce(conn, seq, size, deadline)
+			if err != nil {
+				return false, microerror.Mask(err)
+			}
+			if ok {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	}
+
+	ok, err := passes(hi)
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+	if ok {
+		return hi, nil
+	}
+
+	ok, err = passes(lo)
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+	if !ok {
+		return 0, nil
+	}
+
+	// lo passes, hi does not.
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+
+		ok, err := passes(mid)
+		if err != nil {
+			return 0, microerror.Mask(err)
+		}
+		if ok {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	return lo, nil
+}
+
+// pathMTU returns the MTU of the path on which UDP payloads of the given size
+// pass to the given host.
+func pathMTU(host string, payload int) int {
+	h, _, _ := net.SplitHostPort(host)
+	if ip := net.ParseIP(h); ip != nil && ip.To4() == nil {
+		return payload + ipv6HeaderSize + udpHeaderSize
+	}
+
+	return payload + ipv4HeaderSize + udpHeaderSize
+}
+
+// newMTUPayloadDesc returns the descriptor of the largest passing payload
+// gauge.
+func newMTUPayloadDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "mtu", "max_payload_bytes"),
+		"Largest payload of UDP echo requests with the Don't Fragment bit set answered in the last round.",
+		withPeerLabels("host"),
+		nil,
+	)
+}
@@ -0,0 +1,59 @@
+package network
+
+import (
+	"net"
+	"syscall"
+
+	"github.com/giantswarm/microerror"
+	"golang.org/x/sys/unix"
+)
+
+// dontFragment sets the Don't Fragment bit on all packets of a UDP socket,
+// regardless of the path MTU the kernel has cached for the destination, so
+// that the path itself is probed. It is used as the Control function of a
+// net.Dialer.
+func dontFragment(network string, address string, c syscall.RawConn) error {
+	var sockErr error
+	err := c.Control(func(fd uintptr) {
+		if network == "udp6" {
+			sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_MTU_DISCOVER, unix.IPV6_PMTUDISC_PROBE)
+		} else {
+			sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_MTU_DISCOVER, unix.IP_PMTUDISC_PROBE)
+		}
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if sockErr != nil {
+		return microerror.Mask(sockErr)
+	}
+
+	return nil
+}
+
+// readRouteMTU returns the MTU of the route of the given connected UDP
+// socket.
+func readRouteMTU(conn *net.UDPConn) (int, error) {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+
+	var mtu int
+	var sockErr error
+	err = rawConn.Control(func(fd uintptr) {
+		if conn.RemoteAddr().(*net.UDPAddr).IP.To4() == nil {
+			mtu, sockErr = unix.GetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_MTU)
+		} else {
+			mtu, sockErr = unix.GetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_MTU)
+		}
+	})
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+	if sockErr != nil {
+		return 0, microerror.Mask(sockErr)
+	}
+
+	return mtu, nil
+}
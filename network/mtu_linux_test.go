@@ -0,0 +1,70 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+)
+
+func Test_probeMTU(t *testing.T) {
+	testCases := []struct {
+		name            string
+		inputMaxPayload int
+		expectedPayload int
+	}{
+		{
+			name:            "case 0: all payloads pass on loopback",
+			inputMaxPayload: maxUDPPayload,
+			expectedPayload: maxIPPacketSize - ipv4HeaderSize - udpHeaderSize,
+		},
+		{
+			name:            "case 1: larger payloads are dropped",
+			inputMaxPayload: 1422,
+			expectedPayload: 1422,
+		},
+		{
+			name:            "case 2: nothing passes",
+			inputMaxPayload: 0,
+			expectedPayload: 0,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			defer conn.Close() // nolint: errcheck
+
+			// Echo requests like the UDPResponder, dropping the ones larger
+			// than the maximum payload, like a path with a smaller MTU.
+			go func() {
+				buf := make([]byte, maxUDPPayload)
+				for {
+					n, addr, err := conn.ReadFrom(buf)
+					if err != nil {
+						return
+					}
+					if n <= tc.inputMaxPayload && bytes.HasPrefix(buf[:n], udpEchoMagic) {
+						_, _ = conn.WriteTo(buf[:n], addr)
+					}
+				}
+			}()
+
+			payload, err := probeMTU(context.Background(), microloggertest.New(), &net.Dialer{}, conn.LocalAddr().String(), 2*time.Second)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if payload != tc.expectedPayload {
+				t.Fatalf("payload == %d, want %d", payload, tc.expectedPayload)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+
+package network
+
+import (
+	"net"
+	"syscall"
+
+	"github.com/giantswarm/microerror"
+)
+
+// dontFragment is only supported on Linux.
+func dontFragment(network string, address string, c syscall.RawConn) error {
+	return microerror.Maskf(invalidConfigError, "%T.MTU is only supported on Linux", Config{})
+}
+
+// readRouteMTU is only supported on Linux.
+func readRouteMTU(conn *net.UDPConn) (int, error) {
+	return 0, microerror.Maskf(invalidConfigError, "%T.MTU is only supported on Linux", Config{})
+}
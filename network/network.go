@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -13,8 +14,17 @@ import (
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	"github.com/prometheus/client_golang/prometheus"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	discoveryv1listers "k8s.io/client-go/listers/discovery/v1"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/metadata/metadatalister"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/giantswarm/net-exporter/scheduler"
 )
 
 const (
@@ -24,43 +34,159 @@ const (
 	bucketFactor = 2
 	numBuckets   = 5
 
-	// numNeighbours is the number of neighbours for the net-exporter to dial.
-	// The lower the number, the higher the likelihood that a net-exporter is not dialed
-	// in case of failures - e.g: if numNeighbours is 1, if a single net-exporter is down,
-	// its neighbour will not be pinged.
-	// The higher the number, the higher the cardinality of network latency metrics exposed
-	// by the net-exporter.
-	// Having a value of 2 means that 2 specific net-exporters need to be down
-	// for one net-exporter to not be dialed, without exposing very high cardinality metrics.
-	numNeighbours = 2
+	// podIPIndex is the name of the pod informer index mapping pod IPs to pods.
+	podIPIndex = "podIP"
 )
 
 // Config provides the necessary configuration for creating a Collector.
 type Config struct {
-	Dialer    *net.Dialer
-	K8sClient kubernetes.Interface
-	Logger    micrologger.Logger
+	Dialer *net.Dialer
+	// EndpointSliceInformerFactory must be scoped to Namespace and to the
+	// EndpointSlices of Service, PodInformerFactory to Namespace and the
+	// net-exporter pods, and ServiceInformerFactory to Namespace and Service,
+	// so that every net-exporter only watches the objects it looks up. They
+	// are started by the caller once the Collector has been created.
+	EndpointSliceInformerFactory informers.SharedInformerFactory
+	PodInformerFactory           informers.SharedInformerFactory
+	ServiceInformerFactory       informers.SharedInformerFactory
+	Logger                       micrologger.Logger
+	// NodeInformerFactory must not be scoped to a namespace, and is only used
+	// and required with ZoneAware, to read the topology labels of nodes. It
+	// only caches the metadata of nodes, not their specs and statuses. It is
+	// started by the caller once the Collector has been created.
+	NodeInformerFactory metadatainformer.SharedInformerFactory
 
+	// Interval is the time between two rounds of network dials.
+	Interval  time.Duration
 	Namespace string
-	Port      string
-	Service   string
+	// NodeName and PodName identify this net-exporter in the source labels
+	// of the metrics. They fall back to its endpoint in the EndpointSlices.
+	NodeName string
+	PodName  string
+	// PodIPs are the IPs of this net-exporter, one per IP family. They fall
+	// back to the global unicast IPs of its network interfaces.
+	PodIPs  []string
+	Port    string
+	Service string
+	// Targets are dialed in addition to the service and the neighbours.
+	Targets []Target
+
+	// Topology selects the neighbours among the other net-exporters,
+	// TopologyRing, TopologyMesh or TopologyRotating.
+	Topology string
+	// Neighbours is the number of neighbours of TopologyRing and
+	// TopologyRotating.
+	Neighbours int
+	// MeshMaxSize is the largest number of net-exporters for which
+	// TopologyMesh probes all of them.
+	MeshMaxSize int
+	// ZoneAware adds a neighbour in the zone of this net-exporter and one in
+	// each other zone to the ones selected by Topology, unless they already
+	// cover them, and exposes the latency between zones.
+	ZoneAware bool
+
+	// ICMP enables pinging the neighbours, in addition to dialing them.
+	ICMP bool
+	// ICMPCount is the number of ICMP echo requests sent to a host per round.
+	ICMPCount int
+	// UDP enables sending UDP echo requests to the UDPResponder of the
+	// neighbours on Port, in addition to dialing them.
+	UDP bool
+	// UDPCount is the number of UDP echo requests sent to a host per round.
+	UDPCount int
+	// MTU is the MTU expected on the paths to the neighbours. If set, the
+	// path MTU to each neighbour is probed with UDP echo requests with the
+	// Don't Fragment bit set, which requires UDP. Zero disables it.
+	MTU int
 }
 
 // Collector implements the Collector interface, exposing network latency information.
 type Collector struct {
-	dialer    *net.Dialer
-	k8sClient kubernetes.Interface
-	logger    micrologger.Logger
+	dialer              *net.Dialer
+	endpointSliceLister discoveryv1listers.EndpointSliceLister
+	logger              micrologger.Logger
+	nodeLister          metadatalister.Lister
+	podIndexer          cache.Indexer
+	serviceLister       corev1listers.ServiceLister
 
+	interval  time.Duration
 	namespace string
 	port      string
 	service   string
+	// peerHosts holds the service and neighbour hosts dialed last.
+	peerHosts []string
+	// connectivity holds the result of the last dial of each neighbour,
+	// keyed by address.
+	connectivity map[string]connectivityResult
+	topology     string
+	neighbours   int
+	meshMaxSize  int
+	zoneAware    bool
+	// peerZones holds the locations of the net-exporters probed last, for
+	// the zone latency summaries.
+	peerZones []location
+	// rotation holds the addresses left to probe in the current rotation of
+	// TopologyRotating.
+	rotation []string
+	// peerPingHosts holds the neighbour IPs pinged last.
+	peerPingHosts []string
+	icmp          bool
+	icmpCount     int
+	// peerUDPHosts holds the neighbour hosts sent UDP echo requests last.
+	peerUDPHosts []string
+	udp          bool
+	udpCount     int
+	// peerMTUHosts holds the neighbour hosts whose path MTU was probed last.
+	peerMTUHosts []string
+	mtu          int
+	// targets holds the configured Targets, keyed by host.
+	targets map[string]Target
+	mutex   sync.Mutex
+
+	// peers holds the identity of each net-exporter, keyed by address, and
+	// self the one of this net-exporter at selfAddress, for the peer labels.
+	// They are guarded by peersMutex, so that they are read while holding
+	// mutex.
+	nodeName    string
+	podName     string
+	podIPs      []string
+	peers       map[string]peer
+	self        peer
+	selfAddress string
+	peersMutex  sync.RWMutex
+
+	// tlsResults holds the result of the last TLS handshake of each TLS
+	// Target, keyed by host.
+	tlsResults map[string]tlsResult
+	// pingResults holds the result of the last round of ICMP echo requests to
+	// each neighbour and ICMP Target, keyed by host.
+	pingResults map[string]pingResult
+	// udpResults holds the result of the last round of UDP echo requests to
+	// each neighbour, keyed by host.
+	udpResults map[string]pingResult
+	// mtuResults holds the largest payload passing to each neighbour in the
+	// last round, keyed by host.
+	mtuResults map[string]int
+
+	latencyHistogramVec       *histogramvec.HistogramVec
+	latencyHistogramDesc      *prometheus.Desc
+	echoHistogramVec          *histogramvec.HistogramVec
+	echoHistogramDesc         *prometheus.Desc
+	tlsHandshakeHistogramVec  *histogramvec.HistogramVec
+	tlsHandshakeHistogramDesc *prometheus.Desc
+	udpRTTHistogramVec        *histogramvec.HistogramVec
+	udpRTTHistogramDesc       *prometheus.Desc
 
-	latencyHistogramVec  *histogramvec.HistogramVec
-	latencyHistogramDesc *prometheus.Desc
+	errorCount             prometheus.Counter
+	dialErrorCount         *prometheus.CounterVec
+	echoErrorCount         *prometheus.CounterVec
+	tlsHandshakeErrorCount *prometheus.CounterVec
+	icmpErrorCount         *prometheus.CounterVec
+	udpErrorCount          *prometheus.CounterVec
+	mtuErrorCount          *prometheus.CounterVec
+	mtuFailureCount        *prometheus.CounterVec
 
-	errorCount     prometheus.Counter
-	dialErrorCount *prometheus.CounterVec
+	zoneLatency *prometheus.SummaryVec
 }
 
 // New creates a Collector, given a Config.
@@ -68,24 +194,82 @@ func New(config Config) (*Collector, error) {
 	if config.Dialer == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Dialer must not be empty", config)
 	}
-	if config.K8sClient == nil {
-		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	if config.EndpointSliceInformerFactory == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.EndpointSliceInformerFactory must not be empty", config)
+	}
+	if config.PodInformerFactory == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.PodInformerFactory must not be empty", config)
+	}
+	if config.ServiceInformerFactory == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ServiceInformerFactory must not be empty", config)
 	}
 	if config.Logger == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
 	}
 
+	if config.Interval <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Interval must be greater than zero", config)
+	}
 	if config.Namespace == "" {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Namespace must not be empty", config)
 	}
+	for _, ip := range config.PodIPs {
+		if net.ParseIP(ip) == nil {
+			return nil, microerror.Maskf(invalidConfigError, "%T.PodIPs must hold IPs, got %#q", config, ip)
+		}
+	}
 	if config.Port == "" {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Port must not be empty", config)
 	}
 	if config.Service == "" {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Service must not be empty", config)
 	}
+	if config.Topology != TopologyRing && config.Topology != TopologyMesh && config.Topology != TopologyRotating {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Topology must be %#q, %#q or %#q, got %#q", config, TopologyRing, TopologyMesh, TopologyRotating, config.Topology)
+	}
+	if config.Neighbours <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Neighbours must be greater than zero", config)
+	}
+	if config.MeshMaxSize < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.MeshMaxSize must not be negative", config)
+	}
+	if config.ZoneAware && config.NodeInformerFactory == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.NodeInformerFactory must not be empty with %T.ZoneAware", config, config)
+	}
+	if config.ICMPCount <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ICMPCount must be greater than zero", config)
+	}
+	if config.UDPCount <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.UDPCount must be greater than zero", config)
+	}
+	if config.MTU < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.MTU must not be negative", config)
+	}
+	if config.MTU > 0 && !config.UDP {
+		return nil, microerror.Maskf(invalidConfigError, "%T.MTU requires %T.UDP", config, config)
+	}
+
+	err := ValidateTargets(config.Targets)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
 
-	var err error
+	targets := map[string]Target{}
+	for _, t := range config.Targets {
+		targets[t.Host] = t
+	}
+
+	var podIndexer cache.Indexer
+	{
+		podInformer := config.PodInformerFactory.Core().V1().Pods().Informer()
+
+		err = podInformer.AddIndexers(cache.Indexers{podIPIndex: podIPIndexFunc})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		podIndexer = podInformer.GetIndexer()
+	}
 
 	var latencyHistogramVec *histogramvec.HistogramVec
 	{
@@ -98,6 +282,39 @@ func New(config Config) (*Collector, error) {
 		}
 	}
 
+	var echoHistogramVec *histogramvec.HistogramVec
+	{
+		c := histogramvec.Config{
+			BucketLimits: prometheus.ExponentialBuckets(bucketStart, bucketFactor, numBuckets),
+		}
+		echoHistogramVec, err = histogramvec.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var tlsHandshakeHistogramVec *histogramvec.HistogramVec
+	{
+		c := histogramvec.Config{
+			BucketLimits: prometheus.ExponentialBuckets(bucketStart, bucketFactor, numBuckets),
+		}
+		tlsHandshakeHistogramVec, err = histogramvec.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var udpRTTHistogramVec *histogramvec.HistogramVec
+	{
+		c := histogramvec.Config{
+			BucketLimits: prometheus.ExponentialBuckets(bucketStart, bucketFactor, numBuckets),
+		}
+		udpRTTHistogramVec, err = histogramvec.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	errorCount := prometheus.NewCounter(prometheus.CounterOpts{
 		Name: prometheus.BuildFQName(namespace, "", "error_total"),
 		Help: "Total number of internal errors.",
@@ -107,30 +324,127 @@ func New(config Config) (*Collector, error) {
 			Name: prometheus.BuildFQName(namespace, "", "dial_error_total"),
 			Help: "Total number of errors dialing hosts.",
 		},
+		withPeerLabels("host"),
+	)
+	echoErrorCount := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "echo", "error_total"),
+			Help: "Total number of failed echo requests to net-exporters, by reason.",
+		},
+		withPeerLabels("host", "reason"),
+	)
+	tlsHandshakeErrorCount := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "tls", "handshake_error_total"),
+			Help: "Total number of errors completing TLS handshakes with hosts.",
+		},
 		[]string{"host"},
 	)
+	icmpErrorCount := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "icmp", "error_total"),
+			Help: "Total number of errors sending ICMP echo requests to hosts.",
+		},
+		withPeerLabels("host"),
+	)
+	udpErrorCount := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "udp", "error_total"),
+			Help: "Total number of errors sending UDP echo requests to hosts.",
+		},
+		withPeerLabels("host"),
+	)
+	mtuErrorCount := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "mtu", "error_total"),
+			Help: "Total number of errors probing the path MTU to hosts.",
+		},
+		withPeerLabels("host"),
+	)
+	mtuFailureCount := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "mtu", "failure_total"),
+			Help: "Total number of rounds in which the path MTU to hosts was below the expected MTU.",
+		},
+		withPeerLabels("host"),
+	)
+	zoneLatency := prometheus.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Name:       prometheus.BuildFQName(namespace, "zone", "latency_seconds"),
+			Help:       "Summary of latency of network dials to the net-exporters of other zones, and of the same zone.",
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+		},
+		[]string{"source_region", "source_zone", "destination_region", "destination_zone", "ip_family"},
+	)
 	prometheus.MustRegister(errorCount)
 	prometheus.MustRegister(dialErrorCount)
+	prometheus.MustRegister(echoErrorCount)
+	prometheus.MustRegister(tlsHandshakeErrorCount)
+	prometheus.MustRegister(icmpErrorCount)
+	prometheus.MustRegister(udpErrorCount)
+	prometheus.MustRegister(mtuErrorCount)
+	prometheus.MustRegister(mtuFailureCount)
+	prometheus.MustRegister(zoneLatency)
+
+	var nodeLister metadatalister.Lister
+	if config.ZoneAware {
+		nodes := corev1.SchemeGroupVersion.WithResource("nodes")
+		nodeLister = metadatalister.New(config.NodeInformerFactory.ForResource(nodes).Informer().GetIndexer(), nodes)
+	}
 
 	collector := &Collector{
-		dialer:    config.Dialer,
-		k8sClient: config.K8sClient,
-		logger:    config.Logger,
+		dialer:              config.Dialer,
+		endpointSliceLister: config.EndpointSliceInformerFactory.Discovery().V1().EndpointSlices().Lister(),
+		logger:              config.Logger,
+		nodeLister:          nodeLister,
+		podIndexer:          podIndexer,
+		serviceLister:       config.ServiceInformerFactory.Core().V1().Services().Lister(),
 
+		interval:  config.Interval,
 		namespace: config.Namespace,
+		nodeName:  config.NodeName,
+		podName:   config.PodName,
+		podIPs:    config.PodIPs,
 		port:      config.Port,
 		service:   config.Service,
 
-		latencyHistogramVec: latencyHistogramVec,
-		latencyHistogramDesc: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "latency_seconds"),
-			"Histogram of latency of network dials.",
-			[]string{"host"},
-			nil,
-		),
+		connectivity: map[string]connectivityResult{},
+		topology:     config.Topology,
+		neighbours:   config.Neighbours,
+		meshMaxSize:  config.MeshMaxSize,
+		zoneAware:    config.ZoneAware,
+
+		icmp:      config.ICMP,
+		icmpCount: config.ICMPCount,
+		udp:       config.UDP,
+		udpCount:  config.UDPCount,
+		mtu:       config.MTU,
+		targets:   targets,
 
-		errorCount:     errorCount,
-		dialErrorCount: dialErrorCount,
+		tlsResults:  map[string]tlsResult{},
+		pingResults: map[string]pingResult{},
+		udpResults:  map[string]pingResult{},
+		mtuResults:  map[string]int{},
+
+		latencyHistogramVec:       latencyHistogramVec,
+		latencyHistogramDesc:      newLatencyHistogramDesc(nil),
+		echoHistogramVec:          echoHistogramVec,
+		echoHistogramDesc:         newEchoHistogramDesc(),
+		tlsHandshakeHistogramVec:  tlsHandshakeHistogramVec,
+		tlsHandshakeHistogramDesc: newTLSHandshakeHistogramDesc(nil),
+		udpRTTHistogramVec:        udpRTTHistogramVec,
+		udpRTTHistogramDesc:       newUDPRTTHistogramDesc(),
+
+		errorCount:             errorCount,
+		dialErrorCount:         dialErrorCount,
+		echoErrorCount:         echoErrorCount,
+		tlsHandshakeErrorCount: tlsHandshakeErrorCount,
+		icmpErrorCount:         icmpErrorCount,
+		udpErrorCount:          udpErrorCount,
+		mtuErrorCount:          mtuErrorCount,
+		mtuFailureCount:        mtuFailureCount,
+
+		zoneLatency: zoneLatency,
 	}
 
 	return collector, nil
@@ -139,138 +453,740 @@ func New(config Config) (*Collector, error) {
 // Describe implements the Describe method of the Collector interface.
 func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.latencyHistogramDesc
+	ch <- c.echoHistogramDesc
+	ch <- c.tlsHandshakeHistogramDesc
+	ch <- newTLSNotAfterDesc(nil)
+	ch <- newTLSChainValidDesc(nil)
+	ch <- newTLSInfoDesc(nil)
+	ch <- newICMPLossDesc(nil)
+	for _, statistic := range rttStatistics {
+		ch <- newICMPRTTDesc(statistic, nil)
+	}
+	ch <- newICMPJitterDesc(nil)
+	ch <- c.udpRTTHistogramDesc
+	ch <- newUDPLossDesc()
+	ch <- newMTUPayloadDesc()
+	ch <- newConnectivityUpDesc()
+	ch <- newConnectivityLatencyDesc()
+}
+
+// Jobs implements the Prober interface of the scheduler package.
+func (c *Collector) Jobs() []scheduler.Job {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	jobs := []scheduler.Job{
+		{
+			Name:     namespace,
+			Interval: c.interval,
+			Run:      c.probe,
+		},
+	}
+
+	for host, t := range c.targets {
+		jobs = append(jobs, scheduler.Job{
+			Name:     fmt.Sprintf("%s/%s", namespace, host),
+			Interval: t.Interval,
+			Run: func(ctx context.Context) {
+				c.probeTarget(ctx, host)
+			},
+		})
+	}
+
+	return jobs
+}
+
+// Reconfigure replaces the Targets of the Collector, and removes the metrics
+// of hosts which are not dialed anymore. The jobs of the Collector change
+// accordingly, and must be synced with the scheduler.
+func (c *Collector) Reconfigure(targets []Target) error {
+	err := ValidateTargets(targets)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	newTargets := map[string]Target{}
+	var tlsHosts, icmpHosts []string
+	for _, t := range targets {
+		newTargets[t.Host] = t
+		if t.Protocol == ProtocolTLS {
+			tlsHosts = append(tlsHosts, t.Host)
+		}
+		if t.Protocol == ProtocolICMP {
+			icmpHosts = append(icmpHosts, t.Host)
+		}
+	}
+
+	hosts := append([]string{}, c.peerHosts...)
+	for host, t := range newTargets {
+		if t.Protocol != ProtocolICMP {
+			hosts = append(hosts, host)
+		}
+	}
+
+	for host, t := range c.targets {
+		if !slices.Contains(hosts, host) {
+			c.dialErrorCount.DeletePartialMatch(prometheus.Labels{"host": host})
+		}
+		if t.Protocol == ProtocolTLS && !slices.Contains(tlsHosts, host) {
+			c.tlsHandshakeErrorCount.DeleteLabelValues(host)
+			delete(c.tlsResults, host)
+		}
+		if t.Protocol == ProtocolICMP && !slices.Contains(icmpHosts, host) && !slices.Contains(c.peerPingHosts, host) {
+			c.icmpErrorCount.DeletePartialMatch(prometheus.Labels{"host": host})
+			delete(c.pingResults, host)
+		}
+	}
+
+	c.latencyHistogramVec.Ensure(hosts)
+	c.tlsHandshakeHistogramVec.Ensure(tlsHosts)
+
+	c.targets = newTargets
+
+	return nil
 }
 
 // Collect implements the Collect method of the Collector interface.
 func (c *Collector) Collect(ch chan<- prometheus.Metric) {
-	ctx := context.Background()
-	service, err := c.k8sClient.CoreV1().Services(c.namespace).Get(ctx, c.service, metav1.GetOptions{})
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for host, histogram := range c.latencyHistogramVec.Histograms() {
+		ch <- prometheus.MustNewConstHistogram(
+			newLatencyHistogramDesc(c.targets[host].Labels),
+			histogram.Count(), histogram.Sum(), histogram.Buckets(),
+			c.withPeerLabelValues(host)...,
+		)
+	}
+
+	for host, histogram := range c.echoHistogramVec.Histograms() {
+		ch <- prometheus.MustNewConstHistogram(
+			c.echoHistogramDesc,
+			histogram.Count(), histogram.Sum(), histogram.Buckets(),
+			c.withPeerLabelValues(host)...,
+		)
+	}
+
+	for host, histogram := range c.tlsHandshakeHistogramVec.Histograms() {
+		t, ok := c.targets[host]
+		if !ok || t.Protocol != ProtocolTLS {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstHistogram(
+			newTLSHandshakeHistogramDesc(t.Labels),
+			histogram.Count(), histogram.Sum(), histogram.Buckets(),
+			host,
+		)
+	}
+
+	for host, r := range c.tlsResults {
+		t, ok := c.targets[host]
+		if !ok || t.Protocol != ProtocolTLS {
+			continue
+		}
+
+		chainValid := 0.0
+		if r.chainValid {
+			chainValid = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(newTLSNotAfterDesc(t.Labels), prometheus.GaugeValue, float64(r.notAfter.Unix()), host)
+		ch <- prometheus.MustNewConstMetric(newTLSChainValidDesc(t.Labels), prometheus.GaugeValue, chainValid, host)
+		ch <- prometheus.MustNewConstMetric(newTLSInfoDesc(t.Labels), prometheus.GaugeValue, 1, host, r.version, r.cipherSuite)
+	}
+
+	for host, r := range c.pingResults {
+		var constLabels map[string]string
+		if t, ok := c.targets[host]; ok && t.Protocol == ProtocolICMP {
+			constLabels = t.Labels
+		}
+
+		ch <- prometheus.MustNewConstMetric(newICMPLossDesc(constLabels), prometheus.GaugeValue, r.lossRatio(), c.withPeerLabelValues(host)...)
+
+		// Round trip times are only known if any request was answered.
+		if r.received == 0 {
+			continue
+		}
+
+		rtts := map[string]time.Duration{"min": r.minRTT, "avg": r.avgRTT, "max": r.maxRTT}
+		for _, statistic := range rttStatistics {
+			ch <- prometheus.MustNewConstMetric(newICMPRTTDesc(statistic, constLabels), prometheus.GaugeValue, rtts[statistic].Seconds(), c.withPeerLabelValues(host)...)
+		}
+		ch <- prometheus.MustNewConstMetric(newICMPJitterDesc(constLabels), prometheus.GaugeValue, r.jitter.Seconds(), c.withPeerLabelValues(host)...)
+	}
+
+	for host, histogram := range c.udpRTTHistogramVec.Histograms() {
+		ch <- prometheus.MustNewConstHistogram(
+			c.udpRTTHistogramDesc,
+			histogram.Count(), histogram.Sum(), histogram.Buckets(),
+			c.withPeerLabelValues(host)...,
+		)
+	}
+
+	for host, r := range c.udpResults {
+		ch <- prometheus.MustNewConstMetric(newUDPLossDesc(), prometheus.GaugeValue, r.lossRatio(), c.withPeerLabelValues(host)...)
+	}
+
+	for host, payload := range c.mtuResults {
+		ch <- prometheus.MustNewConstMetric(newMTUPayloadDesc(), prometheus.GaugeValue, float64(payload), c.withPeerLabelValues(host)...)
+	}
+
+	sourceNode := c.sourceNode()
+	for address, r := range c.connectivity {
+		up := 0.0
+		if r.up {
+			up = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(newConnectivityUpDesc(), prometheus.GaugeValue, up, sourceNode, r.node, ipFamily(address))
+		if r.up {
+			ch <- prometheus.MustNewConstMetric(newConnectivityLatencyDesc(), prometheus.GaugeValue, r.latency, sourceNode, r.node, ipFamily(address))
+		}
+	}
+}
+
+func (c *Collector) probe(ctx context.Context) {
+	service, err := c.serviceLister.Services(c.namespace).Get(c.service)
 	if err != nil {
-		c.logger.Log("level", "error", "message", "could not collect service from kubernetes api", "stack", microerror.JSON(err))
+		c.logger.Log("level", "error", "message", "could not collect service from informer cache", "stack", microerror.JSON(err))
 		c.errorCount.Inc()
 		return
 	}
 
 	// Use EndpointSlices instead of Endpoints, filtering by label
-	endpointSliceList, err := c.k8sClient.DiscoveryV1().EndpointSlices(c.namespace).List(ctx, metav1.ListOptions{
-		LabelSelector: fmt.Sprintf("kubernetes.io/service-name=%s", c.service),
-	})
+	endpointSlices, err := c.endpointSliceLister.EndpointSlices(c.namespace).List(labels.SelectorFromSet(labels.Set{
+		discoveryv1.LabelServiceName: c.service,
+	}))
 	if err != nil {
-		c.logger.Log("level", "error", "message", "could not collect endpointslices for service from kubernetes api", "service", c.service, "stack", microerror.JSON(err))
+		c.logger.Log("level", "error", "message", "could not collect endpointslices for service from informer cache", "service", c.service, "stack", microerror.JSON(err))
 		c.errorCount.Inc()
 		return
 	}
 
 	// Aggregate all data from EndpointSlices.
 	var allAddresses []string
-	for _, es := range endpointSliceList.Items {
+	peers := map[string]peer{}
+	for _, es := range endpointSlices {
 		for _, endpoint := range es.Endpoints {
 			allAddresses = append(allAddresses, endpoint.Addresses...)
+			for _, address := range endpoint.Addresses {
+				peers[address] = newPeer(endpoint)
+			}
 		}
 	}
+	if c.zoneAware {
+		c.setNodeTopology(peers)
+	}
 
 	hosts := []string{}
-	hosts = append(hosts, fmt.Sprintf("%v:%v", service.Spec.ClusterIP, c.port))
+	for _, serviceIP := range serviceIPs(service) {
+		hosts = append(hosts, net.JoinHostPort(serviceIP, c.port))
+	}
+	// serviceHosts is the number of hosts of the service, which precede the
+	// ones of the neighbours.
+	serviceHosts := len(hosts)
 
-	neighbours, err := c.getNeighbours(numNeighbours, allAddresses)
+	ip, neighbours, err := c.getNeighbours(allAddresses, peers)
 	if err != nil {
 		c.logger.Log("level", "error", "message", "could not get neighbours", "service", c.service, "stack", microerror.JSON(err))
 		c.errorCount.Inc()
 		return
 	}
 	for _, neighbour := range neighbours {
-		hosts = append(hosts, fmt.Sprintf("%v:%v", neighbour, c.port))
+		hosts = append(hosts, net.JoinHostPort(neighbour, c.port))
 	}
 
+	c.setPeers(ip, peers)
+
 	var wg sync.WaitGroup
 
-	for _, host := range hosts {
+	timeout := c.dialer.Timeout
+	if timeout <= 0 {
+		timeout = c.interval
+	}
+
+	var pingResults []*pingResult
+	if c.icmp {
+		pingResults = make([]*pingResult, len(neighbours))
+
+		for i, neighbour := range neighbours {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				pingResults[i] = c.ping(ctx, neighbour, timeout)
+			}()
+		}
+	}
+
+	var udpHosts []string
+	var udpResults []*pingResult
+	if c.udp {
+		for _, neighbour := range neighbours {
+			udpHosts = append(udpHosts, net.JoinHostPort(neighbour, c.port))
+		}
+		udpResults = make([]*pingResult, len(udpHosts))
+
+		for i, host := range udpHosts {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				udpResults[i] = c.udpEcho(ctx, host, timeout)
+			}()
+		}
+	}
+
+	var mtuResults []*int
+	if c.mtu > 0 {
+		mtuResults = make([]*int, len(udpHosts))
+
+		for i, host := range udpHosts {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				mtuResults[i] = c.probeMTU(ctx, host, timeout)
+			}()
+		}
+	}
+
+	// connectivity holds the result of dialing each neighbour, in the order
+	// of hosts, after the service hosts.
+	connectivity := make([]connectivityResult, len(neighbours))
+
+	for i, host := range hosts {
 		wg.Add(1)
 
 		go func(host string) {
 			defer wg.Done()
 
-			start := time.Now()
+			// Only errors of neighbours may be ignored, as the service IPs
+			// never belong to a pod.
+			isPeer := i >= serviceHosts
 
-			conn, dialErr := c.dialer.Dial("tcp", host)
+			start := time.Now()
+			conn := c.dial(ctx, c.dialer, host, isPeer)
 			elapsed := time.Since(start)
-			if dialErr != nil {
-				pods, err := c.k8sClient.CoreV1().Pods(c.namespace).List(ctx, metav1.ListOptions{
-					FieldSelector: fmt.Sprintf("status.podIP=%s", strings.Split(host, ":")[0]),
-				})
-				if err != nil {
-					c.logger.Log("level", "error", "message", fmt.Sprintf("unable to check if host %#q exists", host), "stack", microerror.JSON(err))
-					c.dialErrorCount.WithLabelValues(host).Inc()
-					return
-				}
 
-				if len(pods.Items) == 0 {
-					c.logger.Log("level", "error", "message", fmt.Sprintf("unable to check if host %#q exists, no pods found, assuming gone", host))
-					return
+			if isPeer {
+				neighbour := neighbours[i-serviceHosts]
+				connectivity[i-serviceHosts] = connectivityResult{
+					node:    peers[neighbour].nodeOr(neighbour),
+					up:      conn != nil,
+					latency: elapsed.Seconds(),
 				}
-				if len(pods.Items) > 1 {
-					c.logger.Log("level", "error", "message", fmt.Sprintf("unable to check if host %#q exists, multiple pods found", host))
-					c.dialErrorCount.WithLabelValues(host).Inc()
-					return
-				}
-
-				if pods.Items[0].GetDeletionTimestamp() == nil {
-					c.logger.Log("level", "error", "message", fmt.Sprintf("host %#q is deleting, ignoring dial error", host))
-					return
-				}
-
-				c.logger.Log("level", "error", "message", fmt.Sprintf("could not dial host %#q", host), "stack", microerror.JSON(dialErr))
-				c.dialErrorCount.WithLabelValues(host).Inc()
-
-				return
 			}
-			defer func() {
-				if err := conn.Close(); err != nil {
-					c.logger.Log("level", "error", "message", fmt.Sprintf("failed to close connection for host %#q", host), "stack", microerror.JSON(err))
-				}
-			}()
 
-			err = c.latencyHistogramVec.Add(host, elapsed.Seconds())
-			if err != nil {
-				c.logger.Log("level", "error", "message", fmt.Sprintf("failed to update latency histogram for host %#q", host), "stack", microerror.JSON(err))
-				c.dialErrorCount.WithLabelValues(host).Inc()
-				return
+			if conn != nil {
+				c.echo(ctx, conn, host, timeout, isPeer)
+				c.closeConn(conn, host)
 			}
 		}(host)
 	}
 
 	wg.Wait()
 
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Results of neighbours probed in earlier rounds are kept, so that the
+	// connectivity matrix covers all pairs with TopologyRotating, until the
+	// neighbour is gone.
+	for address := range c.connectivity {
+		if _, ok := peers[address]; !ok || address == ip {
+			delete(c.connectivity, address)
+		}
+	}
+	for i, neighbour := range neighbours {
+		// Small rings wrap around to this net-exporter.
+		if neighbour != ip {
+			c.connectivity[neighbour] = connectivity[i]
+		}
+	}
+	if c.zoneAware {
+		c.observeZoneLatency(ip, neighbours, connectivity, peers)
+	}
+
+	for _, host := range c.peerHosts {
+		if !slices.Contains(hosts, host) {
+			c.echoErrorCount.DeletePartialMatch(prometheus.Labels{"host": host})
+		}
+	}
+	c.echoHistogramVec.Ensure(hosts)
+
+	c.peerHosts = hosts
+
+	for host, t := range c.targets {
+		if t.Protocol != ProtocolICMP {
+			hosts = append(hosts, host)
+		}
+	}
+
 	c.latencyHistogramVec.Ensure(hosts)
 
-	for host, histogram := range c.latencyHistogramVec.Histograms() {
-		ch <- prometheus.MustNewConstHistogram(
-			c.latencyHistogramDesc,
-			histogram.Count(), histogram.Sum(), histogram.Buckets(),
-			host,
-		)
+	if c.icmp {
+		for _, host := range c.peerPingHosts {
+			if t, ok := c.targets[host]; (!ok || t.Protocol != ProtocolICMP) && !slices.Contains(neighbours, host) {
+				c.icmpErrorCount.DeletePartialMatch(prometheus.Labels{"host": host})
+				delete(c.pingResults, host)
+			}
+		}
+		for i, neighbour := range neighbours {
+			if pingResults[i] == nil {
+				delete(c.pingResults, neighbour)
+				continue
+			}
+			c.pingResults[neighbour] = *pingResults[i]
+		}
+
+		c.peerPingHosts = neighbours
+	}
+
+	if c.udp {
+		for _, host := range c.peerUDPHosts {
+			if !slices.Contains(udpHosts, host) {
+				c.udpErrorCount.DeletePartialMatch(prometheus.Labels{"host": host})
+			}
+		}
+
+		c.udpResults = map[string]pingResult{}
+		for i, host := range udpHosts {
+			if udpResults[i] != nil {
+				c.udpResults[host] = *udpResults[i]
+			}
+		}
+		c.udpRTTHistogramVec.Ensure(udpHosts)
+
+		c.peerUDPHosts = udpHosts
+	}
+
+	if c.mtu > 0 {
+		for _, host := range c.peerMTUHosts {
+			if !slices.Contains(udpHosts, host) {
+				c.mtuErrorCount.DeletePartialMatch(prometheus.Labels{"host": host})
+				c.mtuFailureCount.DeletePartialMatch(prometheus.Labels{"host": host})
+			}
+		}
+
+		c.mtuResults = map[string]int{}
+		for i, host := range udpHosts {
+			if mtuResults[i] != nil {
+				c.mtuResults[host] = *mtuResults[i]
+			}
+		}
+
+		c.peerMTUHosts = udpHosts
+	}
+}
+
+func (c *Collector) probeTarget(ctx context.Context, host string) {
+	c.mutex.Lock()
+	t, ok := c.targets[host]
+	c.mutex.Unlock()
+	if !ok {
+		return
+	}
+
+	dialer := *c.dialer
+	dialer.Timeout = t.Timeout
+
+	if t.Protocol == ProtocolTLS {
+		c.probeTLS(ctx, &dialer, t)
+		return
+	}
+	if t.Protocol == ProtocolICMP {
+		c.setPingResult(t.Host, c.ping(ctx, t.Host, t.Timeout))
+		return
+	}
+
+	conn := c.dial(ctx, &dialer, t.Host, false)
+	if conn != nil {
+		c.closeConn(conn, t.Host)
 	}
 }
 
-func (c *Collector) getNeighbours(n int, addresses []string) ([]string, error) {
-	// Find our IP - note: this does not open a connection, due to UDP.
-	conn, err := net.Dial("udp", "8.8.8.8:80")
+// probeTLS dials the given Target and completes a TLS handshake, recording the
+// latency of both and the result of the handshake. The result is not exposed
+// anymore if either fails.
+func (c *Collector) probeTLS(ctx context.Context, dialer *net.Dialer, t Target) {
+	roots, err := loadRoots(t.CAFile)
 	if err != nil {
-		return nil, microerror.Mask(err)
+		c.logger.Log("level", "error", "message", fmt.Sprintf("could not load CA file for host %#q", t.Host), "stack", microerror.JSON(err))
+		c.errorCount.Inc()
+		return
+	}
+
+	serverName := t.ServerName
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(t.Host)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	conn := c.dial(ctx, dialer, t.Host, false)
+	if conn == nil {
+		c.setTLSResult(t.Host, nil)
+		return
+	}
+	defer c.closeConn(conn, t.Host)
+
+	start := time.Now()
+
+	r, err := handshake(ctx, conn, serverName, roots)
+	if err != nil {
+		c.logger.Log("level", "error", "message", fmt.Sprintf("could not complete TLS handshake with host %#q", t.Host), "stack", microerror.JSON(err))
+		c.tlsHandshakeErrorCount.WithLabelValues(t.Host).Inc()
+		c.setTLSResult(t.Host, nil)
+		return
+	}
+
+	elapsed := time.Since(start)
+
+	if !r.chainValid {
+		c.logger.Log("level", "warning", "message", fmt.Sprintf("certificate chain of host %#q is not valid for %#q", t.Host, serverName))
+	}
+	c.setTLSResult(t.Host, &r)
+
+	err = c.tlsHandshakeHistogramVec.Add(t.Host, elapsed.Seconds())
+	if err != nil {
+		c.logger.Log("level", "error", "message", fmt.Sprintf("failed to update TLS handshake histogram for host %#q", t.Host), "stack", microerror.JSON(err))
+		c.errorCount.Inc()
+		return
 	}
-	defer func() {
-		if err := conn.Close(); err != nil {
-			c.logger.Log("level", "error", "message", "failed to close connection", "stack", microerror.JSON(err))
+}
+
+// setTLSResult sets the result of the last TLS handshake of the given host,
+// or removes it if r is nil. Results of hosts which are not TLS Targets
+// anymore are dropped.
+func (c *Collector) setTLSResult(host string, r *tlsResult) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if t, ok := c.targets[host]; r == nil || !ok || t.Protocol != ProtocolTLS {
+		delete(c.tlsResults, host)
+		return
+	}
+
+	c.tlsResults[host] = *r
+}
+
+// ping sends a round of ICMP echo requests to the given host, returning nil
+// if they could not be sent.
+func (c *Collector) ping(ctx context.Context, host string, timeout time.Duration) *pingResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	r, err := ping(ctx, c.logger, host, c.icmpCount, timeout)
+	if err != nil {
+		c.logger.Log("level", "error", "message", fmt.Sprintf("could not ping host %#q", host), "stack", microerror.JSON(err))
+		c.icmpErrorCount.WithLabelValues(c.withPeerLabelValues(host)...).Inc()
+		return nil
+	}
+
+	return &r
+}
+
+// udpEcho sends a round of UDP echo requests to the UDPResponder on the given
+// host, and records their round trip times. It returns nil if they could not
+// be sent.
+func (c *Collector) udpEcho(ctx context.Context, host string, timeout time.Duration) *pingResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	r, rtts, err := udpEcho(ctx, c.logger, c.dialer, host, c.udpCount, timeout)
+	if err != nil {
+		c.logger.Log("level", "error", "message", fmt.Sprintf("could not send UDP echo requests to host %#q", host), "stack", microerror.JSON(err))
+		c.udpErrorCount.WithLabelValues(c.withPeerLabelValues(host)...).Inc()
+		return nil
+	}
+
+	for _, rtt := range rtts {
+		err = c.udpRTTHistogramVec.Add(host, rtt.Seconds())
+		if err != nil {
+			c.logger.Log("level", "error", "message", fmt.Sprintf("failed to update UDP round trip time histogram for host %#q", host), "stack", microerror.JSON(err))
+			c.errorCount.Inc()
+			break
+		}
+	}
+
+	return &r
+}
+
+// probeMTU probes the largest payload passing to the UDPResponder on the given
+// host, and counts a failure if the path MTU is below the expected one. It
+// returns nil if the path MTU could not be probed.
+func (c *Collector) probeMTU(ctx context.Context, host string, timeout time.Duration) *int {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	payload, err := probeMTU(ctx, c.logger, c.dialer, host, timeout)
+	if err != nil {
+		c.logger.Log("level", "error", "message", fmt.Sprintf("could not probe path MTU to host %#q", host), "stack", microerror.JSON(err))
+		c.mtuErrorCount.WithLabelValues(c.withPeerLabelValues(host)...).Inc()
+		return nil
+	}
+
+	if mtu := pathMTU(host, payload); payload == 0 || mtu < c.mtu {
+		c.logger.Log("level", "warning", "message", fmt.Sprintf("path MTU to host %#q is below the expected MTU", host), "payload", payload, "expected", c.mtu)
+		c.mtuFailureCount.WithLabelValues(c.withPeerLabelValues(host)...).Inc()
+	}
+
+	return &payload
+}
+
+// setPingResult sets the result of the last round of ICMP echo requests to
+// the given ICMP Target, or removes it if r is nil. Results of hosts which are
+// neither ICMP Targets nor neighbours anymore are dropped.
+func (c *Collector) setPingResult(host string, r *pingResult) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if t, ok := c.targets[host]; r == nil || !ok || t.Protocol != ProtocolICMP {
+		if !slices.Contains(c.peerPingHosts, host) {
+			delete(c.pingResults, host)
+		}
+		return
+	}
+
+	c.pingResults[host] = *r
+}
+
+// dial dials the given host and records the latency of the dial. It returns
+// the connection, which must be closed by the caller, or nil if the dial
+// failed. If isPeer is set, host is a net-exporter pod, and dial errors are
+// ignored for pods which are gone or deleting.
+func (c *Collector) dial(ctx context.Context, dialer *net.Dialer, host string, isPeer bool) net.Conn {
+	start := time.Now()
+
+	conn, dialErr := dialer.DialContext(ctx, "tcp", host)
+	elapsed := time.Since(start)
+	if dialErr != nil {
+		if isPeer && c.ignoreDialError(host) {
+			return nil
+		}
+
+		c.logger.Log("level", "error", "message", fmt.Sprintf("could not dial host %#q", host), "stack", microerror.JSON(dialErr))
+		c.dialErrorCount.WithLabelValues(c.withPeerLabelValues(host)...).Inc()
+
+		return nil
+	}
+
+	err := c.latencyHistogramVec.Add(host, elapsed.Seconds())
+	if err != nil {
+		c.logger.Log("level", "error", "message", fmt.Sprintf("failed to update latency histogram for host %#q", host), "stack", microerror.JSON(err))
+		c.dialErrorCount.WithLabelValues(c.withPeerLabelValues(host)...).Inc()
+	}
+
+	return conn
+}
+
+// echo sends an echo request on the given connection to the net-exporter on
+// host, and records its round trip time. If isPeer is set, host is a
+// net-exporter pod, and errors are ignored for pods which are gone or deleting.
+func (c *Collector) echo(ctx context.Context, conn net.Conn, host string, timeout time.Duration, isPeer bool) {
+	elapsed, err := echo(ctx, c.logger, conn, host, timeout)
+	if err != nil {
+		if isPeer && c.ignoreDialError(host) {
+			return
+		}
+
+		reason := echoReasonConnection
+		if IsEchoMismatch(err) {
+			reason = echoReasonMismatch
 		}
-	}()
-	ip := conn.LocalAddr().(*net.UDPAddr).IP.String()
 
-	// Calculate n neighbours, given our local IP and all other net-exporter IPs.
-	neighbours := c.calculateNeighbours(n, ip, addresses)
+		c.logger.Log("level", "error", "message", fmt.Sprintf("could not echo with host %#q", host), "reason", reason, "stack", microerror.JSON(err))
+		c.echoErrorCount.WithLabelValues(c.withPeerLabelValues(host, reason)...).Inc()
+		return
+	}
+
+	err = c.echoHistogramVec.Add(host, elapsed.Seconds())
+	if err != nil {
+		c.logger.Log("level", "error", "message", fmt.Sprintf("failed to update echo histogram for host %#q", host), "stack", microerror.JSON(err))
+		c.errorCount.Inc()
+	}
+}
+
+func (c *Collector) closeConn(conn net.Conn, host string) {
+	if err := conn.Close(); err != nil {
+		c.logger.Log("level", "error", "message", fmt.Sprintf("failed to close connection for host %#q", host), "stack", microerror.JSON(err))
+	}
+}
+
+// ignoreDialError returns true if a dial error for the given net-exporter host
+// can be ignored, because its pod is gone or deleting.
+func (c *Collector) ignoreDialError(host string) bool {
+	ip, _, err := net.SplitHostPort(host)
+	if err != nil {
+		ip = host
+	}
+
+	pods, err := c.podIndexer.ByIndex(podIPIndex, ip)
+	if err != nil {
+		c.logger.Log("level", "error", "message", fmt.Sprintf("unable to check if host %#q exists", host), "stack", microerror.JSON(err))
+		return false
+	}
+
+	if len(pods) == 0 {
+		c.logger.Log("level", "error", "message", fmt.Sprintf("unable to check if host %#q exists, no pods found, assuming gone", host))
+		return true
+	}
+	if len(pods) > 1 {
+		c.logger.Log("level", "error", "message", fmt.Sprintf("unable to check if host %#q exists, multiple pods found", host))
+		return false
+	}
 
-	c.logger.Log("level", "info", "message", "calculated neighbours", "ip", ip, "neighbours", strings.Join(neighbours, ", "))
+	if pods[0].(*corev1.Pod).GetDeletionTimestamp() != nil {
+		c.logger.Log("level", "error", "message", fmt.Sprintf("host %#q is deleting, ignoring dial error", host))
+		return true
+	}
 
-	return neighbours, nil
+	return false
+}
+
+// getNeighbours returns the local IP, and the addresses of the neighbours
+// selected among the given ones according to the topology, and their zones in
+// peers if zone aware, followed by their addresses of the other IP families.
+func (c *Collector) getNeighbours(addresses []string, peers map[string]peer) (string, []string, error) {
+	ip, err := c.getIP(addresses, peers)
+	if err != nil {
+		return "", nil, microerror.Mask(err)
+	}
+
+	// Dual-stack net-exporters select their neighbours among the addresses of
+	// the family of their IP, and then probe them over all families.
+	allAddresses := addresses
+	addresses = sameFamily(ip, addresses)
+
+	// Calculate the neighbours, given our local IP and all other net-exporter IPs.
+	var neighbours []string
+	switch {
+	case c.topology == TopologyMesh && len(addresses) <= c.meshMaxSize:
+		neighbours = calculateMesh(ip, addresses)
+	case c.topology == TopologyRotating:
+		c.mutex.Lock()
+		neighbours = c.rotateNeighbours(c.neighbours, ip, addresses)
+		c.mutex.Unlock()
+	default:
+		neighbours = c.calculateNeighbours(c.neighbours, ip, addresses)
+	}
+	if c.zoneAware {
+		neighbours = calculateZoneNeighbours(ip, neighbours, addresses, peers)
+	}
+	neighbours = withOtherFamilies(ip, neighbours, allAddresses, peers)
+
+	c.logger.Log("level", "info", "message", "calculated neighbours", "ip", ip, "topology", c.topology, "zoneAware", c.zoneAware, "neighbours", strings.Join(neighbours, ", "))
+
+	return ip, neighbours, nil
 }
 
 func (c *Collector) calculateNeighbours(n int, ip string, addresses []string) []string {
@@ -294,3 +1210,29 @@ func (c *Collector) calculateNeighbours(n int, ip string, addresses []string) []
 
 	return neighbours
 }
+
+// newLatencyHistogramDesc returns the descriptor of the latency histograms,
+// with the given constant labels.
+func newLatencyHistogramDesc(constLabels map[string]string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "latency_seconds"),
+		"Histogram of latency of network dials.",
+		withPeerLabels("host"),
+		constLabels,
+	)
+}
+
+// podIPIndexFunc indexes pods by all of their pod IPs.
+func podIPIndexFunc(obj any) ([]string, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil, microerror.Maskf(wrongTypeError, "expected %T, got %T", &corev1.Pod{}, obj)
+	}
+
+	var ips []string
+	for _, podIP := range pod.Status.PodIPs {
+		ips = append(ips, podIP.IP)
+	}
+
+	return ips, nil
+}
@@ -0,0 +1,156 @@
+package network
+
+import (
+	"net"
+	"slices"
+
+	"github.com/giantswarm/exporterkit/histogramvec"
+	"github.com/prometheus/client_golang/prometheus"
+	discoveryv1 "k8s.io/api/discovery/v1"
+)
+
+// peerLabelNames are the labels identifying the net-exporters at both ends
+// of a probe. The destination labels are empty for hosts which are not
+// net-exporter pods, e.g. the service and Targets.
+var peerLabelNames = []string{"source_node", "source_pod", "source_zone", "destination_node", "destination_pod", "destination_zone"}
+
+// peer identifies a net-exporter pod. region is only known from the topology
+// labels of the node.
+type peer struct {
+	node   string
+	pod    string
+	zone   string
+	region string
+}
+
+// newPeer returns the identity of the net-exporter pod of the given
+// EndpointSlice endpoint. The zone falls back to the first zone hint.
+func newPeer(endpoint discoveryv1.Endpoint) peer {
+	var p peer
+	if endpoint.NodeName != nil {
+		p.node = *endpoint.NodeName
+	}
+	if endpoint.TargetRef != nil && endpoint.TargetRef.Kind == "Pod" {
+		p.pod = endpoint.TargetRef.Name
+	}
+	if endpoint.Zone != nil {
+		p.zone = *endpoint.Zone
+	} else if endpoint.Hints != nil && len(endpoint.Hints.ForZones) > 0 {
+		p.zone = endpoint.Hints.ForZones[0].Name
+	}
+
+	return p
+}
+
+// nodeOr returns the node of the peer, or the given address if it is unknown.
+func (p peer) nodeOr(address string) string {
+	if p.node == "" {
+		return address
+	}
+
+	return p.node
+}
+
+// location returns the region and zone of the peer.
+func (p peer) location() location {
+	return location{region: p.region, zone: p.zone}
+}
+
+// withPeerLabels returns the given label names, followed by the ip_family
+// label and peerLabelNames.
+func withPeerLabels(names ...string) []string {
+	return append(append(names, "ip_family"), peerLabelNames...)
+}
+
+// peerLabelValues returns the values of peerLabelNames for the given host,
+// which is an IP, or in host:port form.
+func (c *Collector) peerLabelValues(host string) []string {
+	address := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		address = h
+	}
+
+	c.peersMutex.RLock()
+	defer c.peersMutex.RUnlock()
+
+	destination := c.peers[address]
+
+	return []string{c.self.node, c.self.pod, c.self.zone, destination.node, destination.pod, destination.zone}
+}
+
+// withPeerLabelValues returns the given host and label values, followed by the
+// IP family and the values of peerLabelNames for the given host.
+func (c *Collector) withPeerLabelValues(host string, values ...string) []string {
+	return append(append(append([]string{host}, values...), ipFamily(host)), c.peerLabelValues(host)...)
+}
+
+// setPeers sets the identities of the net-exporters, keyed by address, and the
+// one of this net-exporter at the given IP, which NodeName and PodName
+// override. Counter series, histograms and results of addresses now taken by
+// another net-exporter are deleted, so that they are not exposed under the new
+// identity.
+func (c *Collector) setPeers(ip string, peers map[string]peer) {
+	self := peers[ip]
+	if c.nodeName != "" {
+		self.node = c.nodeName
+	}
+	if c.podName != "" {
+		self.pod = c.podName
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.peersMutex.Lock()
+	defer c.peersMutex.Unlock()
+
+	for address, old := range c.peers {
+		if p, ok := peers[address]; ok && p != old {
+			hosts := []string{address, net.JoinHostPort(address, c.port)}
+
+			deleteHistograms(c.latencyHistogramVec, hosts)
+			deleteHistograms(c.echoHistogramVec, hosts)
+			deleteHistograms(c.udpRTTHistogramVec, hosts)
+
+			for _, host := range hosts {
+				delete(c.pingResults, host)
+				delete(c.udpResults, host)
+				delete(c.mtuResults, host)
+
+				labels := prometheus.Labels{"host": host}
+
+				c.dialErrorCount.DeletePartialMatch(labels)
+				c.echoErrorCount.DeletePartialMatch(labels)
+				c.icmpErrorCount.DeletePartialMatch(labels)
+				c.udpErrorCount.DeletePartialMatch(labels)
+				c.mtuErrorCount.DeletePartialMatch(labels)
+				c.mtuFailureCount.DeletePartialMatch(labels)
+			}
+		}
+	}
+
+	c.peers = peers
+	c.self = self
+	c.selfAddress = ip
+}
+
+// deleteHistograms deletes the histograms of the given hosts from the given
+// HistogramVec, which has no way of deleting single histograms.
+func deleteHistograms(histogramVec *histogramvec.HistogramVec, hosts []string) {
+	var keep []string
+	for host := range histogramVec.Histograms() {
+		if !slices.Contains(hosts, host) {
+			keep = append(keep, host)
+		}
+	}
+
+	histogramVec.Ensure(keep)
+}
+
+// sourceNode returns the node of this net-exporter for the connectivity
+// matrix, or its address if the node is unknown.
+func (c *Collector) sourceNode() string {
+	c.peersMutex.RLock()
+	defer c.peersMutex.RUnlock()
+
+	return c.self.nodeOr(c.selfAddress)
+}
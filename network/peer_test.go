@@ -0,0 +1,143 @@
+package network
+
+import (
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/giantswarm/exporterkit/histogramvec"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+)
+
+func Test_newPeer(t *testing.T) {
+	node := "node-a"
+	zone := "zone-a"
+
+	testCases := []struct {
+		name          string
+		inputEndpoint discoveryv1.Endpoint
+		expectedPeer  peer
+	}{
+		{
+			name:          "case 0: endpoints without identity have empty labels",
+			inputEndpoint: discoveryv1.Endpoint{},
+			expectedPeer:  peer{},
+		},
+		{
+			name: "case 1: node, pod and zone are taken from the endpoint",
+			inputEndpoint: discoveryv1.Endpoint{
+				NodeName:  &node,
+				TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "net-exporter-abcde"},
+				Zone:      &zone,
+			},
+			expectedPeer: peer{node: "node-a", pod: "net-exporter-abcde", zone: "zone-a"},
+		},
+		{
+			name: "case 2: targets other than pods are ignored",
+			inputEndpoint: discoveryv1.Endpoint{
+				NodeName:  &node,
+				TargetRef: &corev1.ObjectReference{Kind: "Node", Name: "node-a"},
+			},
+			expectedPeer: peer{node: "node-a"},
+		},
+		{
+			name: "case 3: the zone falls back to the zone hints",
+			inputEndpoint: discoveryv1.Endpoint{
+				Hints: &discoveryv1.EndpointHints{ForZones: []discoveryv1.ForZone{{Name: "zone-b"}}},
+			},
+			expectedPeer: peer{zone: "zone-b"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			p := newPeer(tc.inputEndpoint)
+
+			if !cmp.Equal(p, tc.expectedPeer, cmp.AllowUnexported(peer{})) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedPeer, p, cmp.AllowUnexported(peer{})))
+			}
+		})
+	}
+}
+
+func Test_Collector_setPeers(t *testing.T) {
+	newHistogramVec := func() *histogramvec.HistogramVec {
+		h, err := histogramvec.New(histogramvec.Config{BucketLimits: []float64{1}})
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		return h
+	}
+	newCounterVec := func() *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{Name: "error_total"}, withPeerLabels("host"))
+	}
+
+	c := &Collector{
+		port: "8000",
+
+		pingResults: map[string]pingResult{},
+		udpResults:  map[string]pingResult{},
+		mtuResults:  map[string]int{},
+
+		latencyHistogramVec: newHistogramVec(),
+		echoHistogramVec:    newHistogramVec(),
+		udpRTTHistogramVec:  newHistogramVec(),
+
+		dialErrorCount:  newCounterVec(),
+		echoErrorCount:  newCounterVec(),
+		icmpErrorCount:  newCounterVec(),
+		udpErrorCount:   newCounterVec(),
+		mtuErrorCount:   newCounterVec(),
+		mtuFailureCount: newCounterVec(),
+	}
+
+	c.setPeers("10.0.0.3", map[string]peer{
+		"10.0.0.1": {node: "node-a", pod: "pod-a"},
+		"10.0.0.2": {node: "node-b", pod: "pod-b"},
+	})
+
+	for _, address := range []string{"10.0.0.1", "10.0.0.2"} {
+		host := net.JoinHostPort(address, c.port)
+		for _, h := range []*histogramvec.HistogramVec{c.latencyHistogramVec, c.echoHistogramVec, c.udpRTTHistogramVec} {
+			err := h.Add(host, 0.5)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+		}
+		c.pingResults[address] = pingResult{sent: 1, received: 1}
+		c.udpResults[host] = pingResult{sent: 1, received: 1}
+		c.mtuResults[host] = 1400
+	}
+
+	// The IP of pod-a is reused by pod-c on another node.
+	c.setPeers("10.0.0.3", map[string]peer{
+		"10.0.0.1": {node: "node-c", pod: "pod-c"},
+		"10.0.0.2": {node: "node-b", pod: "pod-b"},
+	})
+
+	for _, h := range []*histogramvec.HistogramVec{c.latencyHistogramVec, c.echoHistogramVec, c.udpRTTHistogramVec} {
+		histograms := h.Histograms()
+		if _, ok := histograms["10.0.0.1:8000"]; ok {
+			t.Fatalf("histogram of reused address carried over")
+		}
+		if _, ok := histograms["10.0.0.2:8000"]; !ok {
+			t.Fatalf("histogram of unchanged address deleted")
+		}
+	}
+
+	expectedPingResults := map[string]pingResult{"10.0.0.2": {sent: 1, received: 1}}
+	if !cmp.Equal(c.pingResults, expectedPingResults, cmp.AllowUnexported(pingResult{})) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expectedPingResults, c.pingResults, cmp.AllowUnexported(pingResult{})))
+	}
+	expectedUDPResults := map[string]pingResult{"10.0.0.2:8000": {sent: 1, received: 1}}
+	if !cmp.Equal(c.udpResults, expectedUDPResults, cmp.AllowUnexported(pingResult{})) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expectedUDPResults, c.udpResults, cmp.AllowUnexported(pingResult{})))
+	}
+	expectedMTUResults := map[string]int{"10.0.0.2:8000": 1400}
+	if !cmp.Equal(c.mtuResults, expectedMTUResults) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expectedMTUResults, c.mtuResults))
+	}
+}
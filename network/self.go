@@ -0,0 +1,89 @@
+package network
+
+import (
+	"net"
+	"slices"
+
+	"github.com/giantswarm/microerror"
+)
+
+// localIPs returns the IPs of this net-exporter, the configured PodIPs, or the
+// global unicast IPs of its network interfaces without them.
+func (c *Collector) localIPs() ([]string, error) {
+	if len(c.podIPs) > 0 {
+		return c.podIPs, nil
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var ips []string
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if ok && ipNet.IP.IsGlobalUnicast() {
+			ips = append(ips, ipNet.IP.String())
+		}
+	}
+
+	return ips, nil
+}
+
+// getIP returns the IP of this net-exporter among the given net-exporter
+// addresses. It is the first local IP among them, or else the address of the
+// endpoint of this net-exporter, found by NodeName and PodName, e.g. when the
+// pod IPs are not taken from the downward API and the interfaces hold other
+// IPs. It falls back to the first local IP, e.g. while the net-exporter is not
+// ready yet.
+func (c *Collector) getIP(addresses []string, peers map[string]peer) (string, error) {
+	ips, err := c.localIPs()
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	for _, ip := range ips {
+		if slices.Contains(addresses, ip) {
+			return ip, nil
+		}
+	}
+
+	if c.nodeName != "" {
+		var matches []string
+		for _, address := range addresses {
+			p := peers[address]
+			if p.node == c.nodeName && (c.podName == "" || p.pod == c.podName) {
+				matches = append(matches, address)
+			}
+		}
+
+		// Several matches are several pods on the node, or a dual-stack pod,
+		// which is ambiguous without PodName, or by family respectively.
+		if len(matches) == 1 {
+			return matches[0], nil
+		}
+	}
+
+	if len(ips) == 0 {
+		return "", microerror.Maskf(ipNotFoundError, "no pod IPs configured and no global unicast IPs on the interfaces")
+	}
+
+	return ips[0], nil
+}
+
+// sameFamily returns the given addresses of the IP family of the given IP,
+// so that the neighbours of dual-stack net-exporters are selected among
+// addresses of a single family.
+func sameFamily(ip string, addresses []string) []string {
+	isIPv4 := net.ParseIP(ip).To4() != nil
+
+	var family []string
+	for _, address := range addresses {
+		parsed := net.ParseIP(address)
+		if parsed != nil && (parsed.To4() != nil) == isIPv4 {
+			family = append(family, address)
+		}
+	}
+
+	return family
+}
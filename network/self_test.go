@@ -0,0 +1,104 @@
+package network
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Collector_getIP(t *testing.T) {
+	peers := map[string]peer{
+		"10.0.0.1": {node: "node-a", pod: "net-exporter-a"},
+		"10.0.0.2": {node: "node-b", pod: "net-exporter-b"},
+		"10.0.0.3": {node: "node-b", pod: "net-exporter-c"},
+		"fd00::2":  {node: "node-b", pod: "net-exporter-b"},
+	}
+
+	testCases := []struct {
+		name           string
+		inputPodIPs    []string
+		inputNodeName  string
+		inputPodName   string
+		inputAddresses []string
+		expectedIP     string
+	}{
+		{
+			name:           "case 0: the first pod IP among the addresses is picked",
+			inputPodIPs:    []string{"fd00::2", "10.0.0.2"},
+			inputAddresses: []string{"10.0.0.1", "10.0.0.2", "fd00::2"},
+			expectedIP:     "fd00::2",
+		},
+		{
+			name:           "case 1: pod IPs not among the addresses are skipped",
+			inputPodIPs:    []string{"fd00::2", "10.0.0.2"},
+			inputAddresses: []string{"10.0.0.1", "10.0.0.2"},
+			expectedIP:     "10.0.0.2",
+		},
+		{
+			name:           "case 2: the endpoint of the pod on the node is picked",
+			inputPodIPs:    []string{"192.168.0.2"},
+			inputNodeName:  "node-b",
+			inputPodName:   "net-exporter-c",
+			inputAddresses: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"},
+			expectedIP:     "10.0.0.3",
+		},
+		{
+			name:           "case 3: ambiguous endpoints fall back to the first pod IP",
+			inputPodIPs:    []string{"192.168.0.2"},
+			inputNodeName:  "node-b",
+			inputAddresses: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"},
+			expectedIP:     "192.168.0.2",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			c := &Collector{
+				nodeName: tc.inputNodeName,
+				podName:  tc.inputPodName,
+				podIPs:   tc.inputPodIPs,
+			}
+
+			ip, err := c.getIP(tc.inputAddresses, peers)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if ip != tc.expectedIP {
+				t.Fatalf("ip == %#q, want %#q", ip, tc.expectedIP)
+			}
+		})
+	}
+}
+
+func Test_sameFamily(t *testing.T) {
+	addresses := []string{"10.0.0.1", "fd00::1", "10.0.0.2", "::ffff:10.0.0.3", "fd00::2"}
+
+	testCases := []struct {
+		name              string
+		inputIP           string
+		expectedAddresses []string
+	}{
+		{
+			name:              "case 0: IPv4",
+			inputIP:           "10.0.0.1",
+			expectedAddresses: []string{"10.0.0.1", "10.0.0.2", "::ffff:10.0.0.3"},
+		},
+		{
+			name:              "case 1: IPv6",
+			inputIP:           "fd00::2",
+			expectedAddresses: []string{"fd00::1", "fd00::2"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			family := sameFamily(tc.inputIP, addresses)
+
+			if !cmp.Equal(family, tc.expectedAddresses) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedAddresses, family))
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package network
+
+import (
+	"net"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// ProtocolTCP probes a Target by opening a TCP connection.
+	ProtocolTCP = "tcp"
+	// ProtocolTLS probes a Target by opening a TCP connection and completing
+	// a TLS handshake.
+	ProtocolTLS = "tls"
+	// ProtocolICMP probes a Target by sending ICMP echo requests.
+	ProtocolICMP = "icmp"
+)
+
+// reservedLabelNames are the label names set by the Collector itself, which
+// Target labels must not override.
+var reservedLabelNames = withPeerLabels("cipher_suite", "host", "version")
+
+// Target is a host the Collector dials periodically, in addition to the
+// net-exporter service and neighbours.
+type Target struct {
+	// Host is the address to dial, in host:port form, or the host to ping
+	// for ProtocolICMP.
+	Host string
+	// Protocol is the protocol used to probe Host, ProtocolTCP, ProtocolTLS or
+	// ProtocolICMP.
+	Protocol string
+	// ServerName is the name the certificate of a ProtocolTLS Target is
+	// verified for, and sent as SNI. It defaults to the host of Host.
+	ServerName string
+	// CAFile is the path of a PEM file of the certificate authorities the
+	// certificate chain of a ProtocolTLS Target is verified with. It is read
+	// on every probe, and defaults to the system's certificate authorities.
+	CAFile string
+	// Interval is the time between two probes of Host.
+	Interval time.Duration
+	// Timeout is the maximum time a single probe of Host may take.
+	Timeout time.Duration
+	// Labels are added as constant labels to the metrics of Host.
+	Labels map[string]string
+}
+
+// ValidateTargets returns an invalidConfigError describing the first invalid
+// Target, if any.
+func ValidateTargets(targets []Target) error {
+	hosts := map[string]bool{}
+	for i, t := range targets {
+		if t.Protocol == ProtocolICMP {
+			if _, _, err := net.SplitHostPort(t.Host); t.Host == "" || err == nil {
+				return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Host must be a host without port for protocol %#q, got %#q", Config{}, i, ProtocolICMP, t.Host)
+			}
+		} else if _, _, err := net.SplitHostPort(t.Host); err != nil {
+			return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Host must be in host:port form, got %#q", Config{}, i, t.Host)
+		}
+		if hosts[t.Host] {
+			return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Host %#q must be unique", Config{}, i, t.Host)
+		}
+		hosts[t.Host] = true
+
+		if t.Protocol != ProtocolTCP && t.Protocol != ProtocolTLS && t.Protocol != ProtocolICMP {
+			return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Protocol must be %#q, %#q or %#q, got %#q", Config{}, i, ProtocolTCP, ProtocolTLS, ProtocolICMP, t.Protocol)
+		}
+		if t.Protocol != ProtocolTLS && (t.ServerName != "" || t.CAFile != "") {
+			return microerror.Maskf(invalidConfigError, "%T.Targets[%d].ServerName and CAFile must only be set for protocol %#q", Config{}, i, ProtocolTLS)
+		}
+
+		if t.Interval <= 0 {
+			return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Interval must be greater than zero", Config{}, i)
+		}
+		if t.Timeout <= 0 {
+			return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Timeout must be greater than zero", Config{}, i)
+		}
+
+		for name := range t.Labels {
+			if !model.LegacyValidation.IsValidLabelName(name) {
+				return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Labels contains invalid label name %#q", Config{}, i, name)
+			}
+			for _, reserved := range reservedLabelNames {
+				if name == reserved {
+					return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Labels must not contain reserved label name %#q", Config{}, i, name)
+				}
+			}
+		}
+	}
+
+	return nil
+}
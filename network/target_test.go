@@ -0,0 +1,69 @@
+package network
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_ValidateTargets(t *testing.T) {
+	testCases := []struct {
+		name         string
+		inputTargets []Target
+		errorMatcher func(error) bool
+	}{
+		{
+			name: "case 0: valid targets",
+			inputTargets: []Target{
+				{Host: "10.0.0.1:443", Protocol: ProtocolTLS, Interval: time.Minute, Timeout: time.Second, Labels: map[string]string{"scope": "internal"}},
+				{Host: "10.0.0.2", Protocol: ProtocolICMP, Interval: time.Minute, Timeout: time.Second},
+			},
+			errorMatcher: nil,
+		},
+		{
+			name: "case 1: labels must not contain the host label",
+			inputTargets: []Target{
+				{Host: "10.0.0.1:443", Protocol: ProtocolTCP, Interval: time.Minute, Timeout: time.Second, Labels: map[string]string{"host": "a"}},
+			},
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 2: labels must not contain peer labels",
+			inputTargets: []Target{
+				{Host: "10.0.0.1:443", Protocol: ProtocolTCP, Interval: time.Minute, Timeout: time.Second, Labels: map[string]string{"destination_node": "a"}},
+			},
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 3: labels must not contain the ip_family label",
+			inputTargets: []Target{
+				{Host: "10.0.0.1:443", Protocol: ProtocolTCP, Interval: time.Minute, Timeout: time.Second, Labels: map[string]string{"ip_family": "ipv4"}},
+			},
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 4: hosts of icmp targets must not have a port",
+			inputTargets: []Target{
+				{Host: "10.0.0.2:80", Protocol: ProtocolICMP, Interval: time.Minute, Timeout: time.Second},
+			},
+			errorMatcher: IsInvalidConfig,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			err := ValidateTargets(tc.inputTargets)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"os"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// tlsResult is the outcome of a TLS handshake.
+type tlsResult struct {
+	// chainValid is whether the certificate chain presented by the server is
+	// valid for the server name, and signed by a trusted authority.
+	chainValid bool
+	// notAfter is the earliest expiry of the certificates presented by the
+	// server.
+	notAfter    time.Time
+	version     string
+	cipherSuite string
+}
+
+// handshake completes a TLS handshake on the given connection. The
+// certificate chain is verified separately, so that the handshake succeeds,
+// and the certificates can be inspected, even if the chain is invalid. roots
+// may be nil to verify with the system's certificate authorities.
+func handshake(ctx context.Context, conn net.Conn, serverName string, roots *x509.CertPool) (tlsResult, error) {
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName: serverName,
+		// The chain is verified below.
+		InsecureSkipVerify: true, // nolint: gosec
+	})
+
+	err := tlsConn.HandshakeContext(ctx)
+	if err != nil {
+		return tlsResult{}, microerror.Mask(err)
+	}
+
+	state := tlsConn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return tlsResult{}, microerror.Maskf(tlsHandshakeFailedError, "server presented no certificates")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, verifyErr := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Intermediates: intermediates,
+		Roots:         roots,
+	})
+
+	notAfter := state.PeerCertificates[0].NotAfter
+	for _, cert := range state.PeerCertificates[1:] {
+		if cert.NotAfter.Before(notAfter) {
+			notAfter = cert.NotAfter
+		}
+	}
+
+	r := tlsResult{
+		chainValid:  verifyErr == nil,
+		notAfter:    notAfter,
+		version:     tls.VersionName(state.Version),
+		cipherSuite: tls.CipherSuiteName(state.CipherSuite),
+	}
+
+	return r, nil
+}
+
+// loadRoots returns the certificate authorities in the given PEM file, or nil
+// for the system's certificate authorities if the path is empty.
+func loadRoots(caFile string) (*x509.CertPool, error) {
+	if caFile == "" {
+		return nil, nil
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, microerror.Maskf(invalidConfigError, "CA file %#q contains no certificates", caFile)
+	}
+
+	return roots, nil
+}
+
+// newTLSHandshakeHistogramDesc returns the descriptor of the TLS handshake
+// latency histograms, with the given constant labels.
+func newTLSHandshakeHistogramDesc(constLabels map[string]string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "tls", "handshake_seconds"),
+		"Histogram of latency of TLS handshakes.",
+		[]string{"host"},
+		constLabels,
+	)
+}
+
+// newTLSNotAfterDesc returns the descriptor of the certificate expiry gauge,
+// with the given constant labels.
+func newTLSNotAfterDesc(constLabels map[string]string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "tls", "cert_not_after_timestamp_seconds"),
+		"Earliest expiry of the certificates presented in the last TLS handshake, as a Unix timestamp.",
+		[]string{"host"},
+		constLabels,
+	)
+}
+
+// newTLSChainValidDesc returns the descriptor of the chain validity gauge,
+// with the given constant labels.
+func newTLSChainValidDesc(constLabels map[string]string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "tls", "chain_valid"),
+		"Whether the certificate chain presented in the last TLS handshake is valid for the server name and trusted.",
+		[]string{"host"},
+		constLabels,
+	)
+}
+
+// newTLSInfoDesc returns the descriptor of the TLS info metric, with the
+// given constant labels.
+func newTLSInfoDesc(constLabels map[string]string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "tls", "info"),
+		"Protocol version and cipher suite negotiated in the last TLS handshake, as the version and cipher_suite labels.",
+		[]string{"host", "version", "cipher_suite"},
+		constLabels,
+	)
+}
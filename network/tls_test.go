@@ -0,0 +1,79 @@
+package network
+
+import (
+	"context"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func Test_handshake(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	serverRoots := x509.NewCertPool()
+	serverRoots.AddCert(server.Certificate())
+
+	testCases := []struct {
+		name               string
+		inputServerName    string
+		inputRoots         *x509.CertPool
+		expectedChainValid bool
+	}{
+		{
+			name:               "case 0: trusted certificate",
+			inputServerName:    "example.com",
+			inputRoots:         serverRoots,
+			expectedChainValid: true,
+		},
+		{
+			name:               "case 1: trusted certificate for IP",
+			inputServerName:    "127.0.0.1",
+			inputRoots:         serverRoots,
+			expectedChainValid: true,
+		},
+		{
+			name:               "case 2: certificate for another name",
+			inputServerName:    "giantswarm.io",
+			inputRoots:         serverRoots,
+			expectedChainValid: false,
+		},
+		{
+			name:               "case 3: untrusted certificate",
+			inputServerName:    "example.com",
+			inputRoots:         x509.NewCertPool(),
+			expectedChainValid: false,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			conn, err := net.Dial("tcp", server.Listener.Addr().String())
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			defer conn.Close() // nolint: errcheck
+
+			r, err := handshake(context.Background(), conn, tc.inputServerName, tc.inputRoots)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if r.chainValid != tc.expectedChainValid {
+				t.Fatalf("chainValid == %v, want %v", r.chainValid, tc.expectedChainValid)
+			}
+			if !r.notAfter.Equal(server.Certificate().NotAfter) {
+				t.Fatalf("notAfter == %v, want %v", r.notAfter, server.Certificate().NotAfter)
+			}
+			if r.version != "TLS 1.3" {
+				t.Fatalf("version == %#q, want %#q", r.version, "TLS 1.3")
+			}
+			if r.cipherSuite == "" {
+				t.Fatalf("cipherSuite == %#q, want non-empty", r.cipherSuite)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package network
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// TopologyRing probes the Neighbours next net-exporters by sorted IP.
+	TopologyRing = "ring"
+	// TopologyMesh probes all other net-exporters, as long as there are at
+	// most MeshMaxSize net-exporters, and falls back to TopologyRing
+	// otherwise.
+	TopologyMesh = "mesh"
+	// TopologyRotating probes Neighbours other net-exporters per round, in a
+	// random order which covers all of them before any is probed again.
+	TopologyRotating = "rotating"
+
+	// DefaultNeighbours is the default number of neighbours for the
+	// net-exporter to dial.
+	// The lower the number, the higher the likelihood that a net-exporter is not dialed
+	// in case of failures - e.g: if the number is 1, if a single net-exporter is down,
+	// its neighbour will not be pinged.
+	// The higher the number, the higher the cardinality of network latency metrics exposed
+	// by the net-exporter.
+	// Having a value of 2 means that 2 specific net-exporters need to be down
+	// for one net-exporter to not be dialed, without exposing very high cardinality metrics.
+	DefaultNeighbours = 2
+)
+
+// connectivityResult is the outcome of the last dial of a neighbour, for the
+// connectivity matrix.
+type connectivityResult struct {
+	node    string
+	up      bool
+	latency float64
+}
+
+// calculateMesh returns all addresses but the given IP, sorted.
+func calculateMesh(ip string, addresses []string) []string {
+	neighbours := []string{}
+	for _, address := range addresses {
+		if address != ip {
+			neighbours = append(neighbours, address)
+		}
+	}
+	sort.Strings(neighbours)
+
+	return neighbours
+}
+
+// rotateNeighbours returns the next n addresses of the rotation, which holds
+// all addresses but the given IP in random order. Addresses which are gone are
+// skipped, and a new rotation starts once the current one is exhausted, so
+// that every address is returned once per rotation. Addresses of the new
+// rotation which were already returned from the previous one in the same call
+// are moved to its end, so that they are not skipped in the new rotation.
+func (c *Collector) rotateNeighbours(n int, ip string, addresses []string) []string {
+	others := calculateMesh(ip, addresses)
+	n = min(n, len(others))
+
+	neighbours := []string{}
+	for len(neighbours) < n {
+		if len(c.rotation) == 0 {
+			c.rotation = append([]string{}, others...)
+			rand.Shuffle(len(c.rotation), func(i, j int) { // nolint: gosec
+				c.rotation[i], c.rotation[j] = c.rotation[j], c.rotation[i]
+			})
+		}
+
+		address := c.rotation[0]
+		c.rotation = c.rotation[1:]
+
+		if !slices.Contains(others, address) {
+			continue
+		}
+		if slices.Contains(neighbours, address) {
+			c.rotation = append(c.rotation, address)
+			continue
+		}
+
+		neighbours = append(neighbours, address)
+	}
+
+	return neighbours
+}
+
+// newConnectivityUpDesc returns the descriptor of the connectivity matrix
+// gauge.
+func newConnectivityUpDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "connectivity", "up"),
+		"Whether the last dial from the net-exporter on the source node to the one on the destination node succeeded.",
+		[]string{"source_node", "destination_node", "ip_family"},
+		nil,
+	)
+}
+
+// newConnectivityLatencyDesc returns the descriptor of the connectivity
+// matrix latency gauge.
+func newConnectivityLatencyDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "connectivity", "latency_seconds"),
+		"Latency of the last successful dial from the net-exporter on the source node to the one on the destination node.",
+		[]string{"source_node", "destination_node", "ip_family"},
+		nil,
+	)
+}
@@ -0,0 +1,136 @@
+package network
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func Test_calculateMesh(t *testing.T) {
+	testCases := []struct {
+		name               string
+		inputIP            string
+		inputAddresses     []string
+		expectedNeighbours []string
+	}{
+		{
+			name:               "case 0",
+			inputIP:            "127.0.0.1",
+			inputAddresses:     []string{},
+			expectedNeighbours: []string{},
+		},
+		{
+			name:               "case 1",
+			inputIP:            "127.0.0.1",
+			inputAddresses:     []string{"127.0.0.1"},
+			expectedNeighbours: []string{},
+		},
+		{
+			name:               "case 2",
+			inputIP:            "127.0.0.2",
+			inputAddresses:     []string{"127.0.0.3", "127.0.0.2", "127.0.0.1"},
+			expectedNeighbours: []string{"127.0.0.1", "127.0.0.3"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			neighbours := calculateMesh(tc.inputIP, tc.inputAddresses)
+
+			if !cmp.Equal(neighbours, tc.expectedNeighbours) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedNeighbours, neighbours))
+			}
+		})
+	}
+}
+
+func Test_Collector_rotateNeighbours(t *testing.T) {
+	testCases := []struct {
+		name           string
+		inputN         int
+		inputAddresses []string
+		expectedRounds int
+	}{
+		{
+			name:           "case 0: a single other address is probed every round",
+			inputN:         2,
+			inputAddresses: []string{"127.0.0.1", "127.0.0.2"},
+			expectedRounds: 1,
+		},
+		{
+			name:           "case 1: all other addresses are covered in a rotation",
+			inputN:         2,
+			inputAddresses: []string{"127.0.0.1", "127.0.0.2", "127.0.0.3", "127.0.0.4", "127.0.0.5"},
+			expectedRounds: 2,
+		},
+		{
+			name:           "case 2: no other addresses",
+			inputN:         2,
+			inputAddresses: []string{"127.0.0.1"},
+			expectedRounds: 1,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			c := &Collector{}
+
+			others := calculateMesh("127.0.0.1", tc.inputAddresses)
+
+			var probed []string
+			for range tc.expectedRounds {
+				neighbours := c.rotateNeighbours(tc.inputN, "127.0.0.1", tc.inputAddresses)
+				if len(neighbours) != min(tc.inputN, len(others)) {
+					t.Fatalf("len(neighbours) == %d, want %d", len(neighbours), min(tc.inputN, len(others)))
+				}
+				probed = append(probed, neighbours...)
+			}
+
+			slices.Sort(probed)
+			probed = slices.Compact(probed)
+
+			if !cmp.Equal(probed, others, cmpopts.EquateEmpty()) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(others, probed))
+			}
+		})
+	}
+}
+
+func Test_Collector_rotateNeighbours_refill(t *testing.T) {
+	n := 2
+	addresses := []string{"127.0.0.1", "127.0.0.2", "127.0.0.3", "127.0.0.4", "127.0.0.5", "127.0.0.6"}
+	others := calculateMesh("127.0.0.1", addresses)
+	rounds := (len(others) + n - 1) / n
+
+	// The new rotation is shuffled, so try often enough to start it with the
+	// address which is left over from the previous rotation.
+	for i := range 100 {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			c := &Collector{
+				rotation: []string{"127.0.0.2"},
+			}
+
+			// The first address is the one left over from the previous
+			// rotation, all following ones are taken from the new rotation.
+			neighbours := c.rotateNeighbours(n, "127.0.0.1", addresses)
+			if neighbours[0] != "127.0.0.2" {
+				t.Fatalf("neighbours[0] == %#q, want %#q", neighbours[0], "127.0.0.2")
+			}
+
+			probed := neighbours[1:]
+			for range rounds - 1 {
+				probed = append(probed, c.rotateNeighbours(n, "127.0.0.1", addresses)...)
+			}
+
+			slices.Sort(probed)
+			probed = slices.Compact(probed)
+
+			if !cmp.Equal(probed, others) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(others, probed))
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// udpEchoMagic starts every UDP echo request. The UDPResponder only answers
+// datagrams starting with it, so that it does not reflect arbitrary traffic.
+var udpEchoMagic = []byte("net-exporter/udp-echo/1")
+
+// udpEchoSize is the minimum size of UDP echo requests: the magic, a sequence
+// number and a random nonce, so that replies to earlier requests are not
+// mistaken for the current one. Larger requests are padded with zeros.
+var udpEchoSize = len(udpEchoMagic) + 4 + 8
+
+// maxUDPPayload is the largest possible UDP payload.
+const maxUDPPayload = 65535
+
+// UDPResponderConfig provides the necessary configuration for creating a
+// UDPResponder.
+type UDPResponderConfig struct {
+	Logger micrologger.Logger
+
+	// Address is the UDP address to listen on, e.g. ":8000".
+	Address string
+}
+
+// UDPResponder answers the UDP echo requests the Collectors of the other
+// net-exporters send to their neighbours.
+type UDPResponder struct {
+	logger micrologger.Logger
+
+	conn net.PacketConn
+}
+
+// NewUDPResponder creates a UDPResponder listening on the configured address,
+// given a UDPResponderConfig.
+func NewUDPResponder(config UDPResponderConfig) (*UDPResponder, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.Address == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Address must not be empty", config)
+	}
+
+	conn, err := net.ListenPacket("udp", config.Address)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	r := &UDPResponder{
+		logger: config.Logger,
+
+		conn: conn,
+	}
+
+	return r, nil
+}
+
+// Run answers UDP echo requests until the given context is done.
+func (r *UDPResponder) Run(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		err := r.conn.Close()
+		if err != nil {
+			r.logger.Log("level", "error", "message", "could not close UDP echo listener", "stack", microerror.JSON(err))
+		}
+	}()
+
+	buf := make([]byte, maxUDPPayload)
+	for {
+		n, addr, err := r.conn.ReadFrom(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		} else if err != nil {
+			r.logger.Log("level", "error", "message", "could not read UDP echo request", "stack", microerror.JSON(err))
+			continue
+		}
+
+		if n < udpEchoSize || !bytes.HasPrefix(buf[:n], udpEchoMagic) {
+			continue
+		}
+
+		_, err = r.conn.WriteTo(buf[:n], addr)
+		if err != nil {
+			r.logger.Log("level", "error", "message", "could not answer UDP echo request", "address", addr.String(), "stack", microerror.JSON(err))
+		}
+	}
+}
+
+// udpEcho sends count UDP echo requests to the UDPResponder on the given host,
+// one after the other, waiting up to timeout divided by count for each reply.
+// It returns the round trip times of the replies, and a summary of the round.
+// Requests refused by the host count as lost.
+func udpEcho(ctx context.Context, logger micrologger.Logger, dialer *net.Dialer, host string, count int, timeout time.Duration) (pingResult, []time.Duration, error) {
+	conn, err := dialer.DialContext(ctx, "udp", host)
+	if err != nil {
+		return pingResult{}, nil, microerror.Mask(err)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			logger.Log("level", "error", "message", fmt.Sprintf("failed to close UDP echo connection for host %#q", host), "stack", microerror.JSON(err))
+		}
+	}()
+
+	packetTimeout := timeout / time.Duration(count)
+
+	var rtts []time.Duration
+	for seq := range count {
+		if ctx.Err() != nil {
+			return pingResult{}, nil, microerror.Mask(ctx.Err())
+		}
+
+		deadline := time.Now().Add(packetTimeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+
+		rtt, ok, err := udpEchoOnce(conn, uint32(seq), udpEchoSize, deadline) // nolint: gosec
+		if err != nil {
+			return pingResult{}, nil, microerror.Mask(err)
+		}
+		if ok {
+			rtts = append(rtts, rtt)
+		}
+	}
+
+	return newPingResult(count, rtts), rtts, nil
+}
+
+// udpEchoOnce sends a single UDP echo request of the given size on the given
+// connection, and waits for its reply until deadline. It returns false if no
+// reply arrived in time, or if the request is too large to be sent.
+func udpEchoOnce(conn net.Conn, seq uint32, size int, deadline time.Time) (time.Duration, bool, error) {
+	request := make([]byte, size)
+	copy(request, udpEchoMagic)
+	binary.BigEndian.PutUint32(request[len(udpEchoMagic):], seq)
+	_, err := rand.Read(request[len(udpEchoMagic)+4:])
+	if err != nil {
+		return 0, false, microerror.Mask(err)
+	}
+
+	err = conn.SetDeadline(deadline)
+	if err != nil {
+		return 0, false, microerror.Mask(err)
+	}
+
+	start := time.Now()
+
+	_, err = conn.Write(request)
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EMSGSIZE) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, microerror.Mask(err)
+	}
+
+	// Larger replies do not match the request either.
+	reply := make([]byte, size+1)
+	for {
+		n, err := conn.Read(reply)
+		if errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, syscall.ECONNREFUSED) {
+			return 0, false, nil
+		} else if err != nil {
+			return 0, false, microerror.Mask(err)
+		}
+
+		if bytes.Equal(reply[:n], request) {
+			return time.Since(start), true, nil
+		}
+	}
+}
+
+// newUDPRTTHistogramDesc returns the descriptor of the UDP round trip time
+// histograms.
+func newUDPRTTHistogramDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "udp", "rtt_seconds"),
+		"Histogram of round trip times of UDP echo requests.",
+		withPeerLabels("host"),
+		nil,
+	)
+}
+
+// newUDPLossDesc returns the descriptor of the UDP packet loss gauge.
+func newUDPLossDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "udp", "packet_loss_ratio"),
+		"Ratio of UDP echo requests without reply in the last round.",
+		withPeerLabels("host"),
+		nil,
+	)
+}
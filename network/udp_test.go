@@ -0,0 +1,99 @@
+package network
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+)
+
+func Test_udpEcho(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	responder, err := NewUDPResponder(UDPResponderConfig{
+		Logger:  microloggertest.New(),
+		Address: "127.0.0.1:0",
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	go responder.Run(ctx)
+
+	// A bound socket which never answers.
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	defer silent.Close() // nolint: errcheck
+
+	testCases := []struct {
+		name              string
+		inputHost         string
+		expectedReceived  int
+		expectedLossRatio float64
+	}{
+		{
+			name:              "case 0: the responder answers every request",
+			inputHost:         responder.conn.LocalAddr().String(),
+			expectedReceived:  3,
+			expectedLossRatio: 0,
+		},
+		{
+			name:              "case 1: requests without reply are lost",
+			inputHost:         silent.LocalAddr().String(),
+			expectedReceived:  0,
+			expectedLossRatio: 1,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			r, rtts, err := udpEcho(context.Background(), microloggertest.New(), &net.Dialer{}, tc.inputHost, 3, 300*time.Millisecond)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if r.received != tc.expectedReceived || len(rtts) != tc.expectedReceived {
+				t.Fatalf("received %d replies with %d round trip times, want %d", r.received, len(rtts), tc.expectedReceived)
+			}
+			if r.lossRatio() != tc.expectedLossRatio {
+				t.Fatalf("lossRatio == %v, want %v", r.lossRatio(), tc.expectedLossRatio)
+			}
+		})
+	}
+}
+
+func Test_UDPResponder_ignoresForeignDatagrams(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	responder, err := NewUDPResponder(UDPResponderConfig{
+		Logger:  microloggertest.New(),
+		Address: "127.0.0.1:0",
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	go responder.Run(ctx)
+
+	conn, err := net.Dial("udp", responder.conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	defer conn.Close() // nolint: errcheck
+
+	_, err = conn.Write([]byte("not an echo request"))
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	n, err := conn.Read(make([]byte, 1500))
+	if err == nil {
+		t.Fatalf("read %d bytes, want no reply", n)
+	}
+}
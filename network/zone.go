@@ -0,0 +1,132 @@
+package network
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/giantswarm/microerror"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// location is the region and zone of a net-exporter. Either is empty if
+// unknown.
+type location struct {
+	region string
+	zone   string
+}
+
+// known returns whether the zone of the location is known.
+func (l location) known() bool {
+	return l.zone != ""
+}
+
+// setNodeTopology fills in the zone and region of the given net-exporters
+// from the topology labels of their nodes, where their endpoints do not carry
+// a zone, or in any case for the region.
+func (c *Collector) setNodeTopology(peers map[string]peer) {
+	for address, p := range peers {
+		if p.node == "" {
+			continue
+		}
+
+		node, err := c.nodeLister.Get(p.node)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			c.logger.Log("level", "error", "message", "could not collect node from informer cache", "node", p.node, "stack", microerror.JSON(err))
+			c.errorCount.Inc()
+			continue
+		}
+
+		if p.zone == "" {
+			p.zone = node.Labels[corev1.LabelTopologyZone]
+		}
+		p.region = node.Labels[corev1.LabelTopologyRegion]
+
+		peers[address] = p
+	}
+}
+
+// calculateZoneNeighbours returns the given neighbours, followed by one of the
+// given addresses in the zone of the given IP and one in each other zone, by
+// their zones in peers, unless the neighbours already cover them. Within a
+// zone, the address following the IP by sorted IP is picked, so that the
+// net-exporters of a zone spread their probes across the net-exporters of
+// other zones. Addresses of unknown zone are never picked.
+func calculateZoneNeighbours(ip string, neighbours []string, addresses []string, peers map[string]peer) []string {
+	zones := map[location][]string{}
+	for _, address := range addresses {
+		if l := peers[address].location(); address != ip && l.known() {
+			zones[l] = append(zones[l], address)
+		}
+	}
+
+	covered := map[location]bool{}
+	for _, neighbour := range neighbours {
+		covered[peers[neighbour].location()] = true
+	}
+
+	locations := []location{}
+	for l := range zones {
+		locations = append(locations, l)
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		if locations[i].region != locations[j].region {
+			return locations[i].region < locations[j].region
+		}
+		return locations[i].zone < locations[j].zone
+	})
+
+	zoneNeighbours := append([]string{}, neighbours...)
+	for _, l := range locations {
+		if covered[l] {
+			continue
+		}
+
+		candidates := zones[l]
+		sort.Strings(candidates)
+
+		address := candidates[sort.SearchStrings(candidates, ip)%len(candidates)]
+
+		if !slices.Contains(zoneNeighbours, address) {
+			zoneNeighbours = append(zoneNeighbours, address)
+		}
+	}
+
+	return zoneNeighbours
+}
+
+// observeZoneLatency observes the latency of the successful dials to the given
+// neighbours in the zone latency summaries, by the locations of this
+// net-exporter at the given IP and of the neighbours. Summaries of zones which
+// are gone are deleted.
+func (c *Collector) observeZoneLatency(ip string, neighbours []string, connectivity []connectivityResult, peers map[string]peer) {
+	zones := []location{}
+	for _, p := range peers {
+		if p.location().known() && !slices.Contains(zones, p.location()) {
+			zones = append(zones, p.location())
+		}
+	}
+	for _, l := range c.peerZones {
+		if !slices.Contains(zones, l) {
+			c.zoneLatency.DeletePartialMatch(prometheus.Labels{"destination_region": l.region, "destination_zone": l.zone})
+		}
+	}
+	c.peerZones = zones
+
+	source := peers[ip].location()
+	if !source.known() {
+		return
+	}
+
+	for i, neighbour := range neighbours {
+		destination := peers[neighbour].location()
+		if neighbour == ip || !connectivity[i].up || !destination.known() {
+			continue
+		}
+
+		c.zoneLatency.WithLabelValues(source.region, source.zone, destination.region, destination.zone, ipFamily(neighbour)).Observe(connectivity[i].latency)
+	}
+}
@@ -0,0 +1,112 @@
+package network
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/metadata/metadatalister"
+	"k8s.io/client-go/tools/cache"
+)
+
+func Test_calculateZoneNeighbours(t *testing.T) {
+	peers := map[string]peer{
+		"10.0.0.1": {zone: "zone-a"},
+		"10.0.0.2": {zone: "zone-a"},
+		"10.0.0.3": {zone: "zone-a"},
+		"10.0.1.1": {zone: "zone-b"},
+		"10.0.1.2": {zone: "zone-b"},
+		"10.0.2.1": {region: "region-b", zone: "zone-a"},
+		"10.0.3.1": {},
+	}
+
+	testCases := []struct {
+		name               string
+		inputIP            string
+		inputNeighbours    []string
+		inputPeers         map[string]peer
+		expectedNeighbours []string
+	}{
+		{
+			name:               "case 0: neighbours are kept without known zones",
+			inputIP:            "10.0.0.1",
+			inputNeighbours:    []string{"10.0.0.2", "10.0.3.1"},
+			inputPeers:         map[string]peer{"10.0.0.1": {}, "10.0.0.2": {}, "10.0.3.1": {}},
+			expectedNeighbours: []string{"10.0.0.2", "10.0.3.1"},
+		},
+		{
+			name:               "case 1: the next address of each other zone is added",
+			inputIP:            "10.0.0.1",
+			inputNeighbours:    []string{"10.0.0.2", "10.0.0.3"},
+			inputPeers:         peers,
+			expectedNeighbours: []string{"10.0.0.2", "10.0.0.3", "10.0.1.1", "10.0.2.1"},
+		},
+		{
+			name:               "case 2: the own zone is added, wrapping around",
+			inputIP:            "10.0.0.3",
+			inputNeighbours:    []string{"10.0.1.1", "10.0.2.1", "10.0.3.1"},
+			inputPeers:         peers,
+			expectedNeighbours: []string{"10.0.1.1", "10.0.2.1", "10.0.3.1", "10.0.0.1"},
+		},
+		{
+			name:               "case 3: neighbours covering all zones are kept",
+			inputIP:            "10.0.1.2",
+			inputNeighbours:    []string{"10.0.0.2", "10.0.1.1", "10.0.2.1"},
+			inputPeers:         peers,
+			expectedNeighbours: []string{"10.0.0.2", "10.0.1.1", "10.0.2.1"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var addresses []string
+			for address := range tc.inputPeers {
+				addresses = append(addresses, address)
+			}
+
+			neighbours := calculateZoneNeighbours(tc.inputIP, tc.inputNeighbours, addresses, tc.inputPeers)
+
+			if !cmp.Equal(neighbours, tc.expectedNeighbours) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedNeighbours, neighbours))
+			}
+		})
+	}
+}
+
+func Test_Collector_setNodeTopology(t *testing.T) {
+	nodes := corev1.SchemeGroupVersion.WithResource("nodes")
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, node := range []*metav1.PartialObjectMetadata{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-a", Labels: map[string]string{corev1.LabelTopologyRegion: "region-a", corev1.LabelTopologyZone: "zone-a"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-b", Labels: map[string]string{corev1.LabelTopologyRegion: "region-a", corev1.LabelTopologyZone: "zone-b"}}},
+	} {
+		err := indexer.Add(node)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+	}
+
+	c := &Collector{
+		nodeLister: metadatalister.New(indexer, nodes),
+	}
+
+	peers := map[string]peer{
+		"10.0.0.1": {node: "node-a"},
+		"10.0.0.2": {node: "node-b", zone: "zone-c"},
+		"10.0.0.3": {node: "node-gone"},
+		"10.0.0.4": {},
+	}
+	c.setNodeTopology(peers)
+
+	expectedPeers := map[string]peer{
+		"10.0.0.1": {node: "node-a", zone: "zone-a", region: "region-a"},
+		"10.0.0.2": {node: "node-b", zone: "zone-c", region: "region-a"},
+		"10.0.0.3": {node: "node-gone"},
+		"10.0.0.4": {},
+	}
+	if !cmp.Equal(peers, expectedPeers, cmp.AllowUnexported(peer{})) {
+		t.Fatalf("\n\n%s\n", cmp.Diff(expectedPeers, peers, cmp.AllowUnexported(peer{})))
+	}
+}
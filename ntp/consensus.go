@@ -0,0 +1,80 @@
+package ntp
+
+import (
+	"sort"
+	"time"
+)
+
+// sample is the clock offset measured against a single server, together with
+// the maximum error of that measurement.
+type sample struct {
+	offset   time.Duration
+	distance time.Duration
+}
+
+// consensus returns the offset of the local clock the given samples agree on,
+// and the number of samples agreeing on it.
+//
+// Each sample claims that the true offset lies within its offset plus or
+// minus its distance. Following Marzullo's algorithm, as NTP itself does, the
+// samples whose intervals overlap the region claimed by most samples are
+// truechimers, and all others are falsetickers. The consensus is the median
+// offset of the truechimers. It returns zero agreeing samples if there are no
+// samples.
+func consensus(samples []sample) (time.Duration, int) {
+	if len(samples) == 0 {
+		return 0, 0
+	}
+
+	type edge struct {
+		offset time.Duration
+		// start is +1 for the lower and -1 for the upper end of an interval.
+		start int
+	}
+
+	var edges []edge
+	for _, s := range samples {
+		edges = append(edges, edge{offset: s.offset - s.distance, start: 1})
+		edges = append(edges, edge{offset: s.offset + s.distance, start: -1})
+	}
+
+	// Lower ends sort before upper ends at the same offset, so that touching
+	// intervals count as overlapping.
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].offset != edges[j].offset {
+			return edges[i].offset < edges[j].offset
+		}
+		return edges[i].start > edges[j].start
+	})
+
+	var best, count int
+	var low, high time.Duration
+	for i, e := range edges {
+		count += e.start
+		if count > best {
+			best = count
+			low = e.offset
+			high = edges[i+1].offset
+		}
+	}
+
+	midpoint := low + (high-low)/2
+
+	var offsets []time.Duration
+	for _, s := range samples {
+		if s.offset-s.distance <= midpoint && midpoint <= s.offset+s.distance {
+			offsets = append(offsets, s.offset)
+		}
+	}
+
+	sort.Slice(offsets, func(i, j int) bool {
+		return offsets[i] < offsets[j]
+	})
+
+	median := offsets[len(offsets)/2]
+	if len(offsets)%2 == 0 {
+		median = offsets[len(offsets)/2-1] + (offsets[len(offsets)/2]-offsets[len(offsets)/2-1])/2
+	}
+
+	return median, len(offsets)
+}
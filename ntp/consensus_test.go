@@ -0,0 +1,75 @@
+package ntp
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_consensus(t *testing.T) {
+	testCases := []struct {
+		name             string
+		inputSamples     []sample
+		expectedOffset   time.Duration
+		expectedAgreeing int
+	}{
+		{
+			name:             "case 0: no samples",
+			inputSamples:     nil,
+			expectedOffset:   0,
+			expectedAgreeing: 0,
+		},
+		{
+			name: "case 1: single sample",
+			inputSamples: []sample{
+				{offset: 5 * time.Millisecond, distance: 10 * time.Millisecond},
+			},
+			expectedOffset:   5 * time.Millisecond,
+			expectedAgreeing: 1,
+		},
+		{
+			name: "case 2: agreeing samples use the median",
+			inputSamples: []sample{
+				{offset: 1 * time.Millisecond, distance: 10 * time.Millisecond},
+				{offset: 3 * time.Millisecond, distance: 10 * time.Millisecond},
+				{offset: 2 * time.Millisecond, distance: 10 * time.Millisecond},
+			},
+			expectedOffset:   2 * time.Millisecond,
+			expectedAgreeing: 3,
+		},
+		{
+			name: "case 3: falseticker is rejected",
+			inputSamples: []sample{
+				{offset: 1 * time.Millisecond, distance: 10 * time.Millisecond},
+				{offset: 3 * time.Millisecond, distance: 10 * time.Millisecond},
+				{offset: 2 * time.Second, distance: 10 * time.Millisecond},
+			},
+			expectedOffset:   2 * time.Millisecond,
+			expectedAgreeing: 2,
+		},
+		{
+			name: "case 4: skewed local clock is detected",
+			inputSamples: []sample{
+				{offset: -2 * time.Second, distance: 20 * time.Millisecond},
+				{offset: -2010 * time.Millisecond, distance: 20 * time.Millisecond},
+				{offset: -1990 * time.Millisecond, distance: 20 * time.Millisecond},
+				{offset: 0, distance: 5 * time.Millisecond},
+			},
+			expectedOffset:   -2 * time.Second,
+			expectedAgreeing: 3,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			offset, agreeing := consensus(tc.inputSamples)
+
+			if offset != tc.expectedOffset {
+				t.Fatalf("offset == %v, want %v", offset, tc.expectedOffset)
+			}
+			if agreeing != tc.expectedAgreeing {
+				t.Fatalf("agreeing == %d, want %d", agreeing, tc.expectedAgreeing)
+			}
+		})
+	}
+}
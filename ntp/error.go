@@ -12,3 +12,21 @@ var invalidConfigError = &microerror.Error{
 func IsInvalidConfig(err error) bool {
 	return microerror.Cause(err) == invalidConfigError
 }
+
+var ntsAuthFailedError = &microerror.Error{
+	Kind: "ntsAuthFailedError",
+}
+
+// IsNTSAuthFailed asserts ntsAuthFailedError.
+func IsNTSAuthFailed(err error) bool {
+	return microerror.Cause(err) == ntsAuthFailedError
+}
+
+var ntsKeyExchangeFailedError = &microerror.Error{
+	Kind: "ntsKeyExchangeFailedError",
+}
+
+// IsNTSKeyExchangeFailed asserts ntsKeyExchangeFailedError.
+func IsNTSKeyExchangeFailed(err error) bool {
+	return microerror.Cause(err) == ntsKeyExchangeFailedError
+}
@@ -0,0 +1,19 @@
+package ntp
+
+import (
+	"time"
+)
+
+// kernelState is the synchronization state of the kernel clock, as disciplined
+// by the NTP daemon of the node, e.g. chrony or systemd-timesyncd.
+type kernelState struct {
+	// synchronized is whether the clock is synchronized, i.e. the kernel
+	// clock state is not TIME_ERROR.
+	synchronized bool
+	// estimatedError is the estimated error of the clock.
+	estimatedError time.Duration
+	// maxError is the maximum error of the clock.
+	maxError time.Duration
+	// taiOffset is the offset of International Atomic Time from UTC.
+	taiOffset time.Duration
+}
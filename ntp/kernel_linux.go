@@ -0,0 +1,32 @@
+package ntp
+
+import (
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"golang.org/x/sys/unix"
+)
+
+// readKernelState reads the synchronization state of the kernel clock with
+// adjtimex. Reading it does not require any privileges.
+func readKernelState() (kernelState, error) {
+	var timex unix.Timex
+
+	state, err := unix.Adjtimex(&timex)
+	if err != nil {
+		return kernelState{}, microerror.Mask(err)
+	}
+
+	return newKernelState(state, timex), nil
+}
+
+// newKernelState returns the kernelState described by the given adjtimex
+// result.
+func newKernelState(state int, timex unix.Timex) kernelState {
+	return kernelState{
+		synchronized:   state != unix.TIME_ERROR,
+		estimatedError: time.Duration(timex.Esterror) * time.Microsecond,
+		maxError:       time.Duration(timex.Maxerror) * time.Microsecond,
+		taiOffset:      time.Duration(timex.Tai) * time.Second,
+	}
+}
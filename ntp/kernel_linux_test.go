@@ -0,0 +1,61 @@
+package ntp
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/sys/unix"
+)
+
+func Test_newKernelState(t *testing.T) {
+	testCases := []struct {
+		name          string
+		inputState    int
+		inputTimex    unix.Timex
+		expectedState kernelState
+	}{
+		{
+			name:       "case 0: synchronized clock",
+			inputState: unix.TIME_OK,
+			inputTimex: unix.Timex{Esterror: 250, Maxerror: 12000, Tai: 37},
+			expectedState: kernelState{
+				synchronized:   true,
+				estimatedError: 250 * time.Microsecond,
+				maxError:       12 * time.Millisecond,
+				taiOffset:      37 * time.Second,
+			},
+		},
+		{
+			name:       "case 1: pending leap second",
+			inputState: unix.TIME_INS,
+			inputTimex: unix.Timex{Esterror: 1000, Maxerror: 1000},
+			expectedState: kernelState{
+				synchronized:   true,
+				estimatedError: time.Millisecond,
+				maxError:       time.Millisecond,
+			},
+		},
+		{
+			name:       "case 2: unsynchronized clock",
+			inputState: unix.TIME_ERROR,
+			inputTimex: unix.Timex{Status: unix.STA_UNSYNC, Esterror: 16000000, Maxerror: 16000000},
+			expectedState: kernelState{
+				synchronized:   false,
+				estimatedError: 16 * time.Second,
+				maxError:       16 * time.Second,
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			state := newKernelState(tc.inputState, tc.inputTimex)
+
+			if !cmp.Equal(state, tc.expectedState, cmp.AllowUnexported(kernelState{})) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedState, state, cmp.AllowUnexported(kernelState{})))
+			}
+		})
+	}
+}
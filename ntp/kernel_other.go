@@ -0,0 +1,12 @@
+//go:build !linux
+
+package ntp
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+// readKernelState is only supported on Linux.
+func readKernelState() (kernelState, error) {
+	return kernelState{}, microerror.Maskf(invalidConfigError, "%T.KernelState is only supported on Linux", Config{})
+}
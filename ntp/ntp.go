@@ -1,7 +1,9 @@
 package ntp
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"sync"
 	"time"
 
@@ -10,6 +12,8 @@ import (
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/giantswarm/net-exporter/scheduler"
 )
 
 const (
@@ -18,35 +22,91 @@ const (
 	bucketStart  = 0.001
 	bucketFactor = 2
 	numBuckets   = 10
-)
 
-var (
-	latencyHistogramDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "", "latency_seconds"),
-		"Histogram of latency of NTP sync requests.",
-		[]string{"server"},
-		nil,
-	)
+	// ntsStageKeyExchange and ntsStageAuthentication are the stages of NTS in
+	// which errors are counted.
+	ntsStageKeyExchange    = "key_exchange"
+	ntsStageAuthentication = "authentication"
 )
 
+// responseGauge is a gauge exposing a value of the last valid response of
+// each server.
+type responseGauge struct {
+	name  string
+	help  string
+	value func(response *ntp.Response) float64
+}
+
+var responseGauges = []responseGauge{
+	{
+		name:  "offset_seconds",
+		help:  "Estimated offset of the local clock relative to the NTP server.",
+		value: func(r *ntp.Response) float64 { return r.ClockOffset.Seconds() },
+	},
+	{
+		name:  "rtt_seconds",
+		help:  "Round-trip time to the NTP server.",
+		value: func(r *ntp.Response) float64 { return r.RTT.Seconds() },
+	},
+	{
+		name:  "stratum",
+		help:  "Stratum of the NTP server.",
+		value: func(r *ntp.Response) float64 { return float64(r.Stratum) },
+	},
+	{
+		name:  "root_delay_seconds",
+		help:  "Round-trip delay of the NTP server to the reference clock.",
+		value: func(r *ntp.Response) float64 { return r.RootDelay.Seconds() },
+	},
+	{
+		name:  "root_dispersion_seconds",
+		help:  "Maximum error of the NTP server relative to the reference clock.",
+		value: func(r *ntp.Response) float64 { return r.RootDispersion.Seconds() },
+	},
+	{
+		name:  "leap",
+		help:  "Leap indicator of the NTP server: 0 no warning, 1 last minute has 61 seconds, 2 last minute has 59 seconds.",
+		value: func(r *ntp.Response) float64 { return float64(r.Leap) },
+	},
+}
+
 // Config provides the necessary configuration for creating a Collector.
 type Config struct {
 	Logger micrologger.Logger
 
-	NTPServers []string
+	// KernelState enables exposing the synchronization state of the kernel
+	// clock of the node. It is only supported on Linux.
+	KernelState bool
+	Targets     []Target
 }
 
 // Collector implements the Collector interface, exposing DNS latency information.
 type Collector struct {
-	logger micrologger.Logger
+	logger      micrologger.Logger
+	kernelState bool
 
-	ntpServers []string
+	// targets holds the configured Targets, keyed by server.
+	targets map[string]Target
+	// responses holds the last valid response of each server. Servers are
+	// removed when a sync fails, so that no stale values are exposed.
+	responses map[string]*ntp.Response
+	mutex     sync.Mutex
 
-	latencyHistogramVec  *histogramvec.HistogramVec
-	latencyHistogramDesc *prometheus.Desc
+	latencyHistogramVec         *histogramvec.HistogramVec
+	latencyHistogramDesc        *prometheus.Desc
+	ntsKeyExchangeHistogramVec  *histogramvec.HistogramVec
+	ntsKeyExchangeHistogramDesc *prometheus.Desc
+	clockSkewDesc               *prometheus.Desc
+	serversAgreeingDesc         *prometheus.Desc
+	kernelSyncStatusDesc        *prometheus.Desc
+	kernelEstimatedErrorDesc    *prometheus.Desc
+	kernelMaxErrorDesc          *prometheus.Desc
+	kernelTAIOffsetDesc         *prometheus.Desc
 
-	errorCount     prometheus.Counter
-	syncErrorCount *prometheus.CounterVec
+	errorCount           prometheus.Counter
+	invalidResponseCount *prometheus.CounterVec
+	ntsErrorCount        *prometheus.CounterVec
+	syncErrorCount       *prometheus.CounterVec
 }
 
 // New creates a Collector, given a Config.
@@ -55,11 +115,22 @@ func New(config Config) (*Collector, error) {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
 	}
 
-	if len(config.NTPServers) == 0 {
-		return nil, microerror.Maskf(invalidConfigError, "%T.NTPServers must not be empty", config)
+	err := ValidateTargets(config.Targets)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if config.KernelState {
+		_, err = readKernelState()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	targets := map[string]Target{}
+	for _, t := range config.Targets {
+		targets[t.Server] = t
 	}
 
-	var err error
 	var latencyHistogramVec *histogramvec.HistogramVec
 	{
 		c := histogramvec.Config{
@@ -71,10 +142,35 @@ func New(config Config) (*Collector, error) {
 		}
 	}
 
+	var ntsKeyExchangeHistogramVec *histogramvec.HistogramVec
+	{
+		c := histogramvec.Config{
+			BucketLimits: prometheus.ExponentialBuckets(bucketStart, bucketFactor, numBuckets),
+		}
+		ntsKeyExchangeHistogramVec, err = histogramvec.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	errorCount := prometheus.NewCounter(prometheus.CounterOpts{
 		Name: prometheus.BuildFQName(namespace, "", "error_total"),
 		Help: "Total number of internal errors.",
 	})
+	invalidResponseCount := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "", "invalid_response_total"),
+			Help: "Total number of ntp responses failing validation.",
+		},
+		[]string{"server"},
+	)
+	ntsErrorCount := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "nts", "error_total"),
+			Help: "Total number of NTS errors, by stage.",
+		},
+		[]string{"server", "stage"},
+	)
 	syncErrorCount := prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: prometheus.BuildFQName(namespace, "", "sync_error_total"),
@@ -84,18 +180,62 @@ func New(config Config) (*Collector, error) {
 	)
 
 	prometheus.MustRegister(errorCount)
+	prometheus.MustRegister(invalidResponseCount)
+	prometheus.MustRegister(ntsErrorCount)
 	prometheus.MustRegister(syncErrorCount)
 
 	collector := &Collector{
-		logger: config.Logger,
+		logger:      config.Logger,
+		kernelState: config.KernelState,
 
-		ntpServers: config.NTPServers,
+		targets:   targets,
+		responses: map[string]*ntp.Response{},
 
-		latencyHistogramVec:  latencyHistogramVec,
-		latencyHistogramDesc: latencyHistogramDesc,
+		latencyHistogramVec:         latencyHistogramVec,
+		latencyHistogramDesc:        newLatencyHistogramDesc(nil),
+		ntsKeyExchangeHistogramVec:  ntsKeyExchangeHistogramVec,
+		ntsKeyExchangeHistogramDesc: newNTSKeyExchangeHistogramDesc(nil),
+		clockSkewDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "clock_skew_seconds"),
+			"Offset of the local clock the NTP servers agree on, positive if the local clock is behind.",
+			nil,
+			nil,
+		),
+		serversAgreeingDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "servers_agreeing"),
+			"Number of NTP servers agreeing on the offset of the local clock.",
+			nil,
+			nil,
+		),
+		kernelSyncStatusDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "kernel", "sync_status"),
+			"Whether the kernel clock of the node is synchronized by its NTP daemon.",
+			nil,
+			nil,
+		),
+		kernelEstimatedErrorDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "kernel", "estimated_error_seconds"),
+			"Estimated error of the kernel clock of the node.",
+			nil,
+			nil,
+		),
+		kernelMaxErrorDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "kernel", "max_error_seconds"),
+			"Maximum error of the kernel clock of the node.",
+			nil,
+			nil,
+		),
+		kernelTAIOffsetDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "kernel", "tai_offset_seconds"),
+			"Offset of International Atomic Time from UTC known to the kernel of the node.",
+			nil,
+			nil,
+		),
 
-		errorCount:     errorCount,
-		syncErrorCount: syncErrorCount,
+		errorCount:           errorCount,
+		invalidResponseCount: invalidResponseCount,
+		ntsErrorCount:        ntsErrorCount,
+		syncErrorCount:       syncErrorCount,
 	}
 
 	return collector, nil
@@ -104,20 +244,81 @@ func New(config Config) (*Collector, error) {
 // Describe implements the Describe method of the Collector interface.
 func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.latencyHistogramDesc
+	ch <- c.ntsKeyExchangeHistogramDesc
+	for _, g := range responseGauges {
+		ch <- newResponseGaugeDesc(g, nil)
+	}
+	ch <- newReferenceIDDesc(nil)
+	ch <- c.clockSkewDesc
+	ch <- c.serversAgreeingDesc
+	if c.kernelState {
+		ch <- c.kernelSyncStatusDesc
+		ch <- c.kernelEstimatedErrorDesc
+		ch <- c.kernelMaxErrorDesc
+		ch <- c.kernelTAIOffsetDesc
+	}
 }
 
-func (c *Collector) ntpsync(ntpServer string, latencyHistogramVec *histogramvec.HistogramVec) {
+func (c *Collector) ntpsync(ctx context.Context, t Target, latencyHistogramVec *histogramvec.HistogramVec) {
+	ntpServer := t.Server
+	address := t.Server
+	options := ntp.QueryOptions{
+		Timeout: t.Timeout,
+		Dialer: func(localAddress, remoteAddress string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "udp", remoteAddress)
+		},
+	}
+
+	if t.NTS {
+		session, err := c.ntsKeyExchange(ctx, t)
+		if err != nil {
+			c.logger.Log("level", "error", "message", fmt.Sprintf("failed NTS key exchange with ntp server %#q", ntpServer), "stack", microerror.JSON(err))
+			c.ntsErrorCount.WithLabelValues(ntpServer, ntsStageKeyExchange).Inc()
+			c.failSync(ntpServer)
+			return
+		}
+
+		address = session.address
+		options.Extensions = []ntp.Extension{session.extension()}
+	}
+
+	// The NTP client does not take a context, so the deadline of the sync
+	// bounds its timeout, and the dial is bound to the context.
+	if d, ok := ctx.Deadline(); ok && time.Until(d) < options.Timeout {
+		options.Timeout = time.Until(d)
+	}
+
 	start := time.Now()
 
-	_, err := ntp.Time(ntpServer)
+	response, err := ntp.QueryWithOptions(address, options)
 	if err != nil {
 		c.logger.Log("level", "error", "message", fmt.Sprintf("failed to sync time with ntp server %#q", ntpServer), "stack", microerror.JSON(err))
-		c.syncErrorCount.WithLabelValues(ntpServer).Inc()
+		if IsNTSAuthFailed(err) {
+			c.ntsErrorCount.WithLabelValues(ntpServer, ntsStageAuthentication).Inc()
+		}
+		c.failSync(ntpServer)
 		return
 	}
 
 	elapsed := time.Since(start)
 
+	// An invalid response still completes the sync, so it is not counted as a
+	// sync error, but its values are not exposed.
+	err = response.Validate()
+	if err != nil {
+		c.logger.Log("level", "warning", "message", fmt.Sprintf("invalid response of ntp server %#q", ntpServer), "stack", microerror.JSON(err))
+		c.invalidResponseCount.WithLabelValues(ntpServer).Inc()
+
+		c.mutex.Lock()
+		delete(c.responses, ntpServer)
+		c.mutex.Unlock()
+	} else {
+		c.mutex.Lock()
+		c.responses[ntpServer] = response
+		c.mutex.Unlock()
+	}
+
 	err = latencyHistogramVec.Add(ntpServer, elapsed.Seconds())
 	if err != nil {
 		c.logger.Log("level", "error", "message", fmt.Sprintf("failed to update latency histogram for ntp server %#q", ntpServer), "stack", microerror.JSON(err))
@@ -126,27 +327,247 @@ func (c *Collector) ntpsync(ntpServer string, latencyHistogramVec *histogramvec.
 	}
 }
 
-// Collect implements the Collect method of the Collector interface.
-func (c *Collector) Collect(ch chan<- prometheus.Metric) {
-	var wg sync.WaitGroup
+// ntsKeyExchange performs the NTS-KE handshake for the given Target, and
+// records its latency.
+func (c *Collector) ntsKeyExchange(ctx context.Context, t Target) (*ntsSession, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	session, err := ntsKeyExchange(ctx, c.logger, t.Server, nil)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	err = c.ntsKeyExchangeHistogramVec.Add(t.Server, time.Since(start).Seconds())
+	if err != nil {
+		c.logger.Log("level", "error", "message", fmt.Sprintf("failed to update NTS key exchange histogram for ntp server %#q", t.Server), "stack", microerror.JSON(err))
+		c.errorCount.Inc()
+	}
+
+	return session, nil
+}
+
+// failSync counts a failed sync with the given server, and stops exposing its
+// last response.
+func (c *Collector) failSync(ntpServer string) {
+	c.syncErrorCount.WithLabelValues(ntpServer).Inc()
+
+	c.mutex.Lock()
+	delete(c.responses, ntpServer)
+	c.mutex.Unlock()
+}
+
+// Jobs implements the Prober interface of the scheduler package.
+func (c *Collector) Jobs() []scheduler.Job {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
-	for _, ntpServer := range c.ntpServers {
-		wg.Add(1)
-		go func(host string) {
-			defer wg.Done()
-			c.ntpsync(host, c.latencyHistogramVec)
-		}(ntpServer)
+	var jobs []scheduler.Job
+
+	for server, t := range c.targets {
+		jobs = append(jobs, scheduler.Job{
+			Name:     fmt.Sprintf("%s/%s", namespace, server),
+			Interval: t.Interval,
+			Run: func(ctx context.Context) {
+				c.probe(ctx, server)
+			},
+		})
+	}
+
+	return jobs
+}
+
+// Reconfigure replaces the Targets of the Collector, and removes the metrics
+// of servers which are not probed anymore. The jobs of the Collector change
+// accordingly, and must be synced with the scheduler.
+func (c *Collector) Reconfigure(targets []Target) error {
+	err := ValidateTargets(targets)
+	if err != nil {
+		return microerror.Mask(err)
 	}
 
-	wg.Wait()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
-	c.latencyHistogramVec.Ensure(c.ntpServers)
+	newTargets := map[string]Target{}
+	var servers []string
+	var ntsServers []string
+	for _, t := range targets {
+		newTargets[t.Server] = t
+		servers = append(servers, t.Server)
+		if t.NTS {
+			ntsServers = append(ntsServers, t.Server)
+		}
+	}
+
+	for server, t := range c.targets {
+		newTarget, ok := newTargets[server]
+		if !ok {
+			c.invalidResponseCount.DeleteLabelValues(server)
+			c.syncErrorCount.DeleteLabelValues(server)
+			delete(c.responses, server)
+		}
+		if t.NTS && !newTarget.NTS {
+			c.ntsErrorCount.DeletePartialMatch(prometheus.Labels{"server": server})
+		}
+	}
+
+	c.latencyHistogramVec.Ensure(servers)
+	c.ntsKeyExchangeHistogramVec.Ensure(ntsServers)
+
+	c.targets = newTargets
+
+	return nil
+}
+
+// Collect implements the Collect method of the Collector interface.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
 	for ntpServer, histogram := range c.latencyHistogramVec.Histograms() {
+		t, ok := c.targets[ntpServer]
+		if !ok {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstHistogram(
+			newLatencyHistogramDesc(t.Labels),
+			histogram.Count(), histogram.Sum(), histogram.Buckets(),
+			ntpServer,
+		)
+	}
+
+	for ntpServer, histogram := range c.ntsKeyExchangeHistogramVec.Histograms() {
+		t, ok := c.targets[ntpServer]
+		if !ok || !t.NTS {
+			continue
+		}
+
 		ch <- prometheus.MustNewConstHistogram(
-			c.latencyHistogramDesc,
+			newNTSKeyExchangeHistogramDesc(t.Labels),
 			histogram.Count(), histogram.Sum(), histogram.Buckets(),
 			ntpServer,
 		)
 	}
+
+	for ntpServer, response := range c.responses {
+		t, ok := c.targets[ntpServer]
+		if !ok {
+			continue
+		}
+
+		for _, g := range responseGauges {
+			ch <- prometheus.MustNewConstMetric(
+				newResponseGaugeDesc(g, t.Labels),
+				prometheus.GaugeValue, g.value(response),
+				ntpServer,
+			)
+		}
+		ch <- prometheus.MustNewConstMetric(
+			newReferenceIDDesc(t.Labels),
+			prometheus.GaugeValue, 1,
+			ntpServer, response.ReferenceString(),
+		)
+	}
+
+	var samples []sample
+	for ntpServer, response := range c.responses {
+		if _, ok := c.targets[ntpServer]; !ok {
+			continue
+		}
+
+		samples = append(samples, sample{
+			offset:   response.ClockOffset,
+			distance: response.RootDistance,
+		})
+	}
+
+	skew, agreeing := consensus(samples)
+	if agreeing > 0 {
+		ch <- prometheus.MustNewConstMetric(c.clockSkewDesc, prometheus.GaugeValue, skew.Seconds())
+	}
+	ch <- prometheus.MustNewConstMetric(c.serversAgreeingDesc, prometheus.GaugeValue, float64(agreeing))
+
+	if c.kernelState {
+		c.collectKernelState(ch)
+	}
+}
+
+// collectKernelState reads the synchronization state of the kernel clock on
+// every scrape, as reading it is cheap and always up to date.
+func (c *Collector) collectKernelState(ch chan<- prometheus.Metric) {
+	state, err := readKernelState()
+	if err != nil {
+		c.logger.Log("level", "error", "message", "failed to read kernel clock state", "stack", microerror.JSON(err))
+		c.errorCount.Inc()
+		return
+	}
+
+	synchronized := 0.0
+	if state.synchronized {
+		synchronized = 1
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.kernelSyncStatusDesc, prometheus.GaugeValue, synchronized)
+	ch <- prometheus.MustNewConstMetric(c.kernelEstimatedErrorDesc, prometheus.GaugeValue, state.estimatedError.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.kernelMaxErrorDesc, prometheus.GaugeValue, state.maxError.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.kernelTAIOffsetDesc, prometheus.GaugeValue, state.taiOffset.Seconds())
+}
+
+func (c *Collector) probe(ctx context.Context, server string) {
+	c.mutex.Lock()
+	t, ok := c.targets[server]
+	c.mutex.Unlock()
+	if !ok {
+		return
+	}
+
+	c.ntpsync(ctx, t, c.latencyHistogramVec)
+}
+
+// newResponseGaugeDesc returns the descriptor of the given response gauge, with
+// the given constant labels.
+func newResponseGaugeDesc(g responseGauge, constLabels map[string]string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", g.name),
+		g.help,
+		[]string{"server"},
+		constLabels,
+	)
+}
+
+// newReferenceIDDesc returns the descriptor of the reference ID info metric,
+// with the given constant labels.
+func newReferenceIDDesc(constLabels map[string]string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "reference_id"),
+		"Reference ID of the NTP server, i.e. the name of its reference clock or the IPv4 address of its upstream server, as the reference_id label.",
+		[]string{"server", "reference_id"},
+		constLabels,
+	)
+}
+
+// newLatencyHistogramDesc returns the descriptor of the latency histograms,
+// with the given constant labels.
+func newLatencyHistogramDesc(constLabels map[string]string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "latency_seconds"),
+		"Histogram of latency of NTP sync requests.",
+		[]string{"server"},
+		constLabels,
+	)
+}
+
+// newNTSKeyExchangeHistogramDesc returns the descriptor of the NTS-KE latency
+// histograms, with the given constant labels.
+func newNTSKeyExchangeHistogramDesc(constLabels map[string]string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "nts", "key_exchange_seconds"),
+		"Histogram of latency of NTS key exchange handshakes.",
+		[]string{"server"},
+		constLabels,
+	)
 }
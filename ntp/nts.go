@@ -0,0 +1,351 @@
+package ntp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+// NTS-KE and NTS extension field constants of RFC 8915.
+const (
+	ntsKEPort  = 4460
+	ntsKEALPN  = "ntske/1"
+	ntsKELabel = "EXPORTER-network-time-security"
+
+	ntsKERecordCritical     = 0x8000
+	ntsKERecordEnd          = 0
+	ntsKERecordNextProtocol = 1
+	ntsKERecordError        = 2
+	ntsKERecordWarning      = 3
+	ntsKERecordAEAD         = 4
+	ntsKERecordCookie       = 5
+	ntsKERecordServer       = 6
+	ntsKERecordPort         = 7
+
+	ntsProtocolNTPv4 = 0
+	// ntsAEADAESSIVCMAC256 is the IANA identifier of AEAD_AES_SIV_CMAC_256.
+	ntsAEADAESSIVCMAC256 = 15
+
+	ntpPort       = 123
+	ntpHeaderSize = 48
+
+	ntsEFUniqueIdentifier = 0x0104
+	ntsEFCookie           = 0x0204
+	ntsEFAuthenticator    = 0x0404
+
+	ntsUniqueIdentifierSize = 32
+	ntsNonceSize            = 16
+)
+
+// ntsSession holds the keys and cookies negotiated by an NTS-KE handshake,
+// which authenticate the NTP queries sent to address.
+type ntsSession struct {
+	address string
+	c2s     *siv
+	s2c     *siv
+	cookies [][]byte
+}
+
+// ntsKeyExchange performs an NTS-KE handshake with the given server, in host
+// or host:port form. The given TLS configuration is used as a base, and may
+// be nil.
+func ntsKeyExchange(ctx context.Context, logger micrologger.Logger, server string, tlsConfig *tls.Config) (*ntsSession, error) {
+	address := server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		address = net.JoinHostPort(server, strconv.Itoa(ntsKEPort))
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	config := &tls.Config{}
+	if tlsConfig != nil {
+		config = tlsConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+	config.MinVersion = tls.VersionTLS13
+	config.NextProtos = []string{ntsKEALPN}
+
+	dialer := &tls.Dialer{Config: config}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, microerror.Maskf(ntsKeyExchangeFailedError, "%s", err)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			logger.Log("level", "error", "message", fmt.Sprintf("failed to close NTS-KE connection to ntp server %#q", server), "stack", microerror.JSON(err))
+		}
+	}()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		err = conn.SetDeadline(deadline)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	tlsConn := conn.(*tls.Conn)
+	if tlsConn.ConnectionState().NegotiatedProtocol != ntsKEALPN {
+		return nil, microerror.Maskf(ntsKeyExchangeFailedError, "server did not negotiate %#q", ntsKEALPN)
+	}
+
+	var request bytes.Buffer
+	writeNTSKERecord(&request, ntsKERecordNextProtocol|ntsKERecordCritical, uint16Bytes(ntsProtocolNTPv4))
+	writeNTSKERecord(&request, ntsKERecordAEAD, uint16Bytes(ntsAEADAESSIVCMAC256))
+	writeNTSKERecord(&request, ntsKERecordEnd|ntsKERecordCritical, nil)
+
+	_, err = tlsConn.Write(request.Bytes())
+	if err != nil {
+		return nil, microerror.Maskf(ntsKeyExchangeFailedError, "%s", err)
+	}
+
+	session := &ntsSession{}
+	ntpHost := host
+	ntpPortNumber := ntpPort
+	var protocolAgreed, aeadAgreed bool
+
+	reader := bufio.NewReader(tlsConn)
+	for {
+		recordType, body, err := readNTSKERecord(reader)
+		if err != nil {
+			return nil, microerror.Maskf(ntsKeyExchangeFailedError, "%s", err)
+		}
+
+		if recordType == ntsKERecordEnd {
+			break
+		}
+
+		switch recordType {
+		case ntsKERecordNextProtocol:
+			protocolAgreed = len(body) == 2 && binary.BigEndian.Uint16(body) == ntsProtocolNTPv4
+		case ntsKERecordError:
+			return nil, microerror.Maskf(ntsKeyExchangeFailedError, "server returned error record %x", body)
+		case ntsKERecordAEAD:
+			aeadAgreed = len(body) == 2 && binary.BigEndian.Uint16(body) == ntsAEADAESSIVCMAC256
+		case ntsKERecordCookie:
+			session.cookies = append(session.cookies, body)
+		case ntsKERecordServer:
+			ntpHost = string(body)
+		case ntsKERecordPort:
+			if len(body) != 2 {
+				return nil, microerror.Maskf(ntsKeyExchangeFailedError, "port record must be 2 bytes, got %d", len(body))
+			}
+			ntpPortNumber = int(binary.BigEndian.Uint16(body))
+		}
+	}
+
+	if !protocolAgreed {
+		return nil, microerror.Maskf(ntsKeyExchangeFailedError, "server did not agree on NTPv4")
+	}
+	if !aeadAgreed {
+		return nil, microerror.Maskf(ntsKeyExchangeFailedError, "server did not agree on AEAD_AES_SIV_CMAC_256")
+	}
+	if len(session.cookies) == 0 {
+		return nil, microerror.Maskf(ntsKeyExchangeFailedError, "server did not send any cookies")
+	}
+
+	session.c2s, session.s2c, err = exportNTSKeys(tlsConn.ConnectionState())
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	session.address = net.JoinHostPort(ntpHost, strconv.Itoa(ntpPortNumber))
+
+	return session, nil
+}
+
+// exportNTSKeys derives the client-to-server and server-to-client keys from
+// the TLS session of an NTS-KE handshake.
+func exportNTSKeys(state tls.ConnectionState) (*siv, *siv, error) {
+	var keys []*siv
+	for _, direction := range []byte{0, 1} {
+		exportContext := append(uint16Bytes(ntsProtocolNTPv4), uint16Bytes(ntsAEADAESSIVCMAC256)...)
+		exportContext = append(exportContext, direction)
+
+		key, err := state.ExportKeyingMaterial(ntsKELabel, exportContext, sivKeySize)
+		if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+		s, err := newSIV(key)
+		if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+		keys = append(keys, s)
+	}
+
+	return keys[0], keys[1], nil
+}
+
+// extension returns an Extension authenticating a single NTP query with the
+// first cookie of the session.
+func (s *ntsSession) extension() *ntsExtension {
+	return &ntsExtension{session: s}
+}
+
+// ntsExtension implements the Extension interface of the ntp package, adding
+// the NTS extension fields to a query and authenticating its response.
+type ntsExtension struct {
+	session  *ntsSession
+	uniqueID []byte
+}
+
+// ProcessQuery implements the ProcessQuery method of the Extension interface.
+func (e *ntsExtension) ProcessQuery(buf *bytes.Buffer) error {
+	e.uniqueID = make([]byte, ntsUniqueIdentifierSize)
+	_, err := rand.Read(e.uniqueID)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	nonce := make([]byte, ntsNonceSize)
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	writeNTPExtensionField(buf, ntsEFUniqueIdentifier, e.uniqueID)
+	writeNTPExtensionField(buf, ntsEFCookie, e.session.cookies[0])
+
+	// The authenticator covers everything before it.
+	ciphertext, err := e.session.c2s.seal(nil, buf.Bytes(), nonce)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	writeNTPExtensionField(buf, ntsEFAuthenticator, ntsAuthenticator(nonce, ciphertext))
+
+	return nil
+}
+
+// ProcessResponse implements the ProcessResponse method of the Extension
+// interface. It fails with an ntsAuthFailedError unless the response echoes
+// the unique identifier of the query and is authenticated by the session.
+// Unauthenticated responses, like NTS NAKs, fail as well.
+func (e *ntsExtension) ProcessResponse(buf []byte) error {
+	if len(buf) < ntpHeaderSize {
+		return microerror.Maskf(ntsAuthFailedError, "response is too short")
+	}
+
+	var uniqueIDMatch bool
+	for offset := ntpHeaderSize; offset+4 <= len(buf); {
+		fieldType := binary.BigEndian.Uint16(buf[offset:])
+		length := int(binary.BigEndian.Uint16(buf[offset+2:]))
+		if length < 4 || offset+length > len(buf) {
+			return microerror.Maskf(ntsAuthFailedError, "response contains malformed extension field")
+		}
+		body := buf[offset+4 : offset+length]
+
+		switch fieldType {
+		case ntsEFUniqueIdentifier:
+			uniqueIDMatch = subtle.ConstantTimeCompare(body, e.uniqueID) == 1
+		case ntsEFAuthenticator:
+			if !uniqueIDMatch {
+				return microerror.Maskf(ntsAuthFailedError, "response does not match unique identifier of query")
+			}
+
+			nonce, ciphertext, err := parseNTSAuthenticator(body)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			// Fields following the authenticator are not authenticated,
+			// and the new cookies it contains are not needed, as every
+			// probe starts a new session.
+			_, err = e.session.s2c.open(ciphertext, buf[:offset], nonce)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			return nil
+		}
+
+		offset += length
+	}
+
+	return microerror.Maskf(ntsAuthFailedError, "response is not authenticated")
+}
+
+// ntsAuthenticator returns the body of an NTS authenticator extension field.
+func ntsAuthenticator(nonce []byte, ciphertext []byte) []byte {
+	var body bytes.Buffer
+	body.Write(uint16Bytes(uint16(len(nonce))))
+	body.Write(uint16Bytes(uint16(len(ciphertext))))
+	body.Write(padded(nonce))
+	body.Write(padded(ciphertext))
+
+	return body.Bytes()
+}
+
+// parseNTSAuthenticator returns the nonce and ciphertext of the given body of
+// an NTS authenticator extension field.
+func parseNTSAuthenticator(body []byte) ([]byte, []byte, error) {
+	if len(body) < 4 {
+		return nil, nil, microerror.Maskf(ntsAuthFailedError, "authenticator is too short")
+	}
+
+	nonceLength := int(binary.BigEndian.Uint16(body))
+	ciphertextLength := int(binary.BigEndian.Uint16(body[2:]))
+	nonceEnd := 4 + len(padded(make([]byte, nonceLength)))
+	if nonceEnd+ciphertextLength > len(body) {
+		return nil, nil, microerror.Maskf(ntsAuthFailedError, "authenticator is too short")
+	}
+
+	return body[4 : 4+nonceLength], body[nonceEnd : nonceEnd+ciphertextLength], nil
+}
+
+func writeNTSKERecord(buf *bytes.Buffer, recordType uint16, body []byte) {
+	buf.Write(uint16Bytes(recordType))
+	buf.Write(uint16Bytes(uint16(len(body))))
+	buf.Write(body)
+}
+
+// readNTSKERecord returns the type, without the critical bit, and the body of
+// the next NTS-KE record.
+func readNTSKERecord(r io.Reader) (uint16, []byte, error) {
+	header := make([]byte, 4)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return 0, nil, microerror.Mask(err)
+	}
+
+	body := make([]byte, binary.BigEndian.Uint16(header[2:]))
+	_, err = io.ReadFull(r, body)
+	if err != nil {
+		return 0, nil, microerror.Mask(err)
+	}
+
+	return binary.BigEndian.Uint16(header) &^ ntsKERecordCritical, body, nil
+}
+
+// writeNTPExtensionField writes an NTP extension field of RFC 7822, padding
+// the given body to a multiple of four bytes.
+func writeNTPExtensionField(buf *bytes.Buffer, fieldType uint16, body []byte) {
+	body = padded(body)
+
+	buf.Write(uint16Bytes(fieldType))
+	buf.Write(uint16Bytes(uint16(4 + len(body))))
+	buf.Write(body)
+}
+
+// padded returns b padded with zeros to a multiple of four bytes.
+func padded(b []byte) []byte {
+	if len(b)%4 == 0 {
+		return b
+	}
+
+	return append(append([]byte{}, b...), make([]byte, 4-len(b)%4)...)
+}
+
+func uint16Bytes(v uint16) []byte {
+	return binary.BigEndian.AppendUint16(nil, v)
+}
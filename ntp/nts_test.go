@@ -0,0 +1,344 @@
+package ntp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"math/big"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/beevik/ntp"
+	"github.com/giantswarm/micrologger/microloggertest"
+)
+
+// Modes of the NTS stand-in.
+const (
+	standInModeValid        = "valid"
+	standInModeKeyExchange  = "key exchange error"
+	standInModeForgedAnswer = "forged answer"
+	standInModeUnauthentic  = "unauthenticated answer"
+)
+
+const (
+	standInClientKey = 0
+	standInServerKey = 1
+
+	standInStratum = 1
+	// standInRootDispersionQ16 is a root dispersion of 0.25ms.
+	standInRootDispersionQ16 = 0x10
+)
+
+func Test_ntsSync(t *testing.T) {
+	testCases := []struct {
+		name         string
+		mode         string
+		errorMatcher func(error) bool
+	}{
+		{
+			name:         "case 0: authenticated sync",
+			mode:         standInModeValid,
+			errorMatcher: nil,
+		},
+		{
+			name:         "case 1: key exchange error record",
+			mode:         standInModeKeyExchange,
+			errorMatcher: IsNTSKeyExchangeFailed,
+		},
+		{
+			name:         "case 2: forged answer",
+			mode:         standInModeForgedAnswer,
+			errorMatcher: IsNTSAuthFailed,
+		},
+		{
+			name:         "case 3: unauthenticated answer",
+			mode:         standInModeUnauthentic,
+			errorMatcher: IsNTSAuthFailed,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			s := newNTSStandIn(t, tc.mode)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			var response *ntp.Response
+			session, err := ntsKeyExchange(ctx, microloggertest.New(), s.keAddress, &tls.Config{RootCAs: s.rootCAs})
+			if err == nil {
+				response, err = ntp.QueryWithOptions(session.address, ntp.QueryOptions{
+					Timeout:    time.Second,
+					Extensions: []ntp.Extension{session.extension()},
+				})
+			}
+			if err == nil {
+				err = response.Validate()
+			}
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if err == nil && response.Stratum != standInStratum {
+				t.Fatalf("stratum == %d, want %d", response.Stratum, standInStratum)
+			}
+		})
+	}
+}
+
+// ntsStandIn is a minimal NTS server, serving NTS-KE over TLS and
+// authenticated NTP over UDP on the loopback interface.
+type ntsStandIn struct {
+	mode      string
+	keAddress string
+	rootCAs   *x509.CertPool
+
+	udpConn net.PacketConn
+	// keys holds the client-to-server and server-to-client keys of each
+	// cookie handed out.
+	keys  map[string][2]*siv
+	mutex sync.Mutex
+}
+
+func newNTSStandIn(t *testing.T, mode string) *ntsStandIn {
+	certificate, rootCAs := newStandInCertificate(t)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS13,
+		NextProtos:   []string{ntsKEALPN},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	udpConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	t.Cleanup(func() {
+		listener.Close()
+		udpConn.Close()
+	})
+
+	s := &ntsStandIn{
+		mode:      mode,
+		keAddress: listener.Addr().String(),
+		rootCAs:   rootCAs,
+
+		udpConn: udpConn,
+		keys:    map[string][2]*siv{},
+	}
+
+	go s.serveKeyExchange(listener)
+	go s.serveNTP()
+
+	return s
+}
+
+func (s *ntsStandIn) serveKeyExchange(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.keyExchange(conn.(*tls.Conn))
+		conn.Close()
+	}
+}
+
+func (s *ntsStandIn) keyExchange(conn *tls.Conn) {
+	reader := bufio.NewReader(conn)
+	for {
+		recordType, _, err := readNTSKERecord(reader)
+		if err != nil {
+			return
+		}
+		if recordType == ntsKERecordEnd {
+			break
+		}
+	}
+
+	var response bytes.Buffer
+	if s.mode == standInModeKeyExchange {
+		writeNTSKERecord(&response, ntsKERecordError|ntsKERecordCritical, uint16Bytes(0))
+		writeNTSKERecord(&response, ntsKERecordEnd|ntsKERecordCritical, nil)
+		_, _ = conn.Write(response.Bytes())
+		return
+	}
+
+	c2s, s2c, err := exportNTSKeys(conn.ConnectionState())
+	if err != nil {
+		return
+	}
+	cookie := make([]byte, 16)
+	_, _ = rand.Read(cookie)
+
+	s.mutex.Lock()
+	s.keys[string(cookie)] = [2]*siv{c2s, s2c}
+	s.mutex.Unlock()
+
+	host, port, _ := net.SplitHostPort(s.udpConn.LocalAddr().String())
+	portNumber, _ := strconv.Atoi(port)
+
+	writeNTSKERecord(&response, ntsKERecordNextProtocol|ntsKERecordCritical, uint16Bytes(ntsProtocolNTPv4))
+	writeNTSKERecord(&response, ntsKERecordAEAD, uint16Bytes(ntsAEADAESSIVCMAC256))
+	writeNTSKERecord(&response, ntsKERecordCookie, cookie)
+	writeNTSKERecord(&response, ntsKERecordServer, []byte(host))
+	writeNTSKERecord(&response, ntsKERecordPort, uint16Bytes(uint16(portNumber)))
+	writeNTSKERecord(&response, ntsKERecordEnd|ntsKERecordCritical, nil)
+	_, _ = conn.Write(response.Bytes())
+}
+
+func (s *ntsStandIn) serveNTP() {
+	buf := make([]byte, 1024)
+	for {
+		n, addr, err := s.udpConn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		response := s.answer(buf[:n])
+		if response != nil {
+			_, _ = s.udpConn.WriteTo(response, addr)
+		}
+	}
+}
+
+// answer returns the response to the given NTP query, or nil if the query is
+// not authentic.
+func (s *ntsStandIn) answer(query []byte) []byte {
+	var uniqueID, cookie []byte
+	var keys [2]*siv
+	var authentic bool
+	for offset := ntpHeaderSize; offset+4 <= len(query); {
+		length := int(binary.BigEndian.Uint16(query[offset+2:]))
+		if length < 4 || offset+length > len(query) {
+			return nil
+		}
+		body := query[offset+4 : offset+length]
+
+		switch binary.BigEndian.Uint16(query[offset:]) {
+		case ntsEFUniqueIdentifier:
+			uniqueID = body
+		case ntsEFCookie:
+			cookie = body
+		case ntsEFAuthenticator:
+			s.mutex.Lock()
+			keys = s.keys[string(cookie)]
+			s.mutex.Unlock()
+			if keys[standInClientKey] == nil {
+				return nil
+			}
+
+			nonce, ciphertext, err := parseNTSAuthenticator(body)
+			if err != nil {
+				return nil
+			}
+			_, err = keys[standInClientKey].open(ciphertext, query[:offset], nonce)
+			authentic = err == nil
+		}
+
+		offset += length
+	}
+	if !authentic {
+		return nil
+	}
+
+	now := ntpTimestamp(time.Now())
+	header := make([]byte, ntpHeaderSize)
+	// Leap indicator 0, version 4, server mode.
+	header[0] = 0<<6 | 4<<3 | 4
+	header[1] = standInStratum
+	binary.BigEndian.PutUint32(header[8:], standInRootDispersionQ16)
+	copy(header[12:], "GPS")
+	binary.BigEndian.PutUint64(header[16:], now)
+	copy(header[24:32], query[40:48])
+	binary.BigEndian.PutUint64(header[32:], now)
+	binary.BigEndian.PutUint64(header[40:], now)
+
+	var response bytes.Buffer
+	response.Write(header)
+	writeNTPExtensionField(&response, ntsEFUniqueIdentifier, uniqueID)
+	if s.mode == standInModeUnauthentic {
+		return response.Bytes()
+	}
+
+	var plaintext bytes.Buffer
+	newCookie := make([]byte, 16)
+	_, _ = rand.Read(newCookie)
+	writeNTPExtensionField(&plaintext, ntsEFCookie, newCookie)
+
+	nonce := make([]byte, ntsNonceSize)
+	_, _ = rand.Read(nonce)
+	ciphertext, err := keys[standInServerKey].seal(plaintext.Bytes(), response.Bytes(), nonce)
+	if err != nil {
+		return nil
+	}
+	if s.mode == standInModeForgedAnswer {
+		ciphertext[len(ciphertext)-1] ^= 0xff
+	}
+	writeNTPExtensionField(&response, ntsEFAuthenticator, ntsAuthenticator(nonce, ciphertext))
+
+	return response.Bytes()
+}
+
+// ntpTimestamp returns the given time in NTP timestamp format.
+func ntpTimestamp(t time.Time) uint64 {
+	const ntpEpochOffset = 2208988800
+
+	seconds := uint64(t.Unix() + ntpEpochOffset)
+	fraction := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+
+	return seconds<<32 | fraction
+}
+
+// newStandInCertificate returns a self-signed certificate for the loopback
+// interface, and a pool trusting it.
+func newStandInCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(certificate)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, rootCAs
+}
@@ -0,0 +1,58 @@
+package ntp
+
+import (
+	"github.com/giantswarm/microerror"
+	"github.com/miscreant/miscreant.go"
+)
+
+const (
+	// sivKeySize is the key size of AEAD_AES_SIV_CMAC_256, which consists of
+	// a 128 bit MAC key and a 128 bit encryption key.
+	sivKeySize = 32
+)
+
+// siv wraps the AEAD_AES_SIV_CMAC_256 algorithm of RFC 5297, the only
+// algorithm NTS servers are required to support. It is not part of the
+// standard library. A siv must not be used concurrently.
+type siv struct {
+	cipher *miscreant.Cipher
+}
+
+func newSIV(key []byte) (*siv, error) {
+	if len(key) != sivKeySize {
+		return nil, microerror.Maskf(invalidConfigError, "key must be %d bytes, got %d", sivKeySize, len(key))
+	}
+
+	cipher, err := miscreant.NewAESCMACSIV(key)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	s := &siv{
+		cipher: cipher,
+	}
+
+	return s, nil
+}
+
+// seal encrypts and authenticates the given plaintext, authenticating the
+// given associated data items as well. It returns the synthetic IV followed by
+// the ciphertext. NTS passes the nonce as the last associated data item.
+func (s *siv) seal(plaintext []byte, associatedData ...[]byte) ([]byte, error) {
+	out, err := s.cipher.Seal(nil, plaintext, associatedData...)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return out, nil
+}
+
+// open decrypts and verifies the given output of seal.
+func (s *siv) open(ciphertext []byte, associatedData ...[]byte) ([]byte, error) {
+	plaintext, err := s.cipher.Open(nil, ciphertext, associatedData...)
+	if err != nil {
+		return nil, microerror.Maskf(ntsAuthFailedError, "%s", err)
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,109 @@
+package ntp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strconv"
+	"testing"
+)
+
+// Test_siv_seal uses the test vectors of RFC 5297, appendix A.
+func Test_siv_seal(t *testing.T) {
+	testCases := []struct {
+		name           string
+		key            string
+		associatedData []string
+		plaintext      string
+		expectedOutput string
+	}{
+		{
+			name:           "case 0: deterministic authenticated encryption",
+			key:            "fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+			associatedData: []string{"101112131415161718191a1b1c1d1e1f2021222324252627"},
+			plaintext:      "112233445566778899aabbccddee",
+			expectedOutput: "85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c",
+		},
+		{
+			name: "case 1: nonce-based authenticated encryption",
+			key:  "7f7e7d7c7b7a79787776757473727170404142434445464748494a4b4c4d4e4f",
+			associatedData: []string{
+				"00112233445566778899aabbccddeeffdeaddadadeaddadaffeeddccbbaa99887766554433221100",
+				"102030405060708090a0",
+				"09f911029d74e35bd84156c5635688c0",
+			},
+			plaintext:      "7468697320697320736f6d6520706c61696e7465787420746f20656e6372797074207573696e67205349562d414553",
+			expectedOutput: "7bdb6e3b432667eb06f4d14bff2fbd0fcb900f2fddbe404326601965c889bf17dba77ceb094fa663b7a3f748ba8af829ea64ad544a272e9c485b62a3fd5c0d",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			s, err := newSIV(mustDecodeHex(t, tc.key))
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			var associatedData [][]byte
+			for _, ad := range tc.associatedData {
+				associatedData = append(associatedData, mustDecodeHex(t, ad))
+			}
+
+			output, err := s.seal(mustDecodeHex(t, tc.plaintext), associatedData...)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if hex.EncodeToString(output) != tc.expectedOutput {
+				t.Fatalf("output == %x, want %s", output, tc.expectedOutput)
+			}
+
+			plaintext, err := s.open(output, associatedData...)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if hex.EncodeToString(plaintext) != tc.plaintext {
+				t.Fatalf("plaintext == %x, want %s", plaintext, tc.plaintext)
+			}
+		})
+	}
+}
+
+func Test_siv_open(t *testing.T) {
+	s, err := newSIV(bytes.Repeat([]byte{1}, sivKeySize))
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	nonce := []byte("nonce")
+	ad := []byte("associated data")
+	sealed, err := s.seal([]byte("plaintext"), ad, nonce)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	plaintext, err := s.open(sealed, ad, nonce)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if string(plaintext) != "plaintext" {
+		t.Fatalf("plaintext == %q, want %q", plaintext, "plaintext")
+	}
+
+	_, err = s.open(sealed, []byte("other associated data"), nonce)
+	if !IsNTSAuthFailed(err) {
+		t.Fatalf("error == %#v, want ntsAuthFailedError", err)
+	}
+
+	_, err = s.open(sealed[:len(sealed)/2], ad, nonce)
+	if !IsNTSAuthFailed(err) {
+		t.Fatalf("error == %#v, want ntsAuthFailedError", err)
+	}
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	return b
+}
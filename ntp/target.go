@@ -0,0 +1,68 @@
+package ntp
+
+import (
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/prometheus/common/model"
+)
+
+// reservedLabelNames are the label names set by the Collector itself, which
+// Target labels must not override.
+var reservedLabelNames = []string{"reference_id", "server"}
+
+// Target is an NTP server the Collector syncs with periodically.
+type Target struct {
+	// Server is the address of the NTP server, in host or host:port form. If
+	// NTS is set, it is the address of the NTS-KE server instead, with port
+	// 4460 if omitted, and the NTP server is negotiated with it.
+	Server string
+	// NTS enables Network Time Security, authenticating the NTP server by an
+	// NTS-KE handshake before every sync.
+	NTS bool
+	// Interval is the time between two syncs with Server.
+	Interval time.Duration
+	// Timeout is the maximum time a single sync with Server may take.
+	Timeout time.Duration
+	// Labels are added as constant labels to the metrics of Server.
+	Labels map[string]string
+}
+
+// ValidateTargets returns an invalidConfigError describing the first invalid
+// Target, if any.
+func ValidateTargets(targets []Target) error {
+	if len(targets) == 0 {
+		return microerror.Maskf(invalidConfigError, "%T.Targets must not be empty", Config{})
+	}
+
+	servers := map[string]bool{}
+	for i, t := range targets {
+		if t.Server == "" {
+			return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Server must not be empty", Config{}, i)
+		}
+		if servers[t.Server] {
+			return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Server %#q must be unique", Config{}, i, t.Server)
+		}
+		servers[t.Server] = true
+
+		if t.Interval <= 0 {
+			return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Interval must be greater than zero", Config{}, i)
+		}
+		if t.Timeout <= 0 {
+			return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Timeout must be greater than zero", Config{}, i)
+		}
+
+		for name := range t.Labels {
+			if !model.LegacyValidation.IsValidLabelName(name) {
+				return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Labels contains invalid label name %#q", Config{}, i, name)
+			}
+			for _, reserved := range reservedLabelNames {
+				if name == reserved {
+					return microerror.Maskf(invalidConfigError, "%T.Targets[%d].Labels must not contain reserved label name %#q", Config{}, i, name)
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,300 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "scheduler"
+)
+
+// Job is a unit of probing work that the Scheduler runs periodically in the
+// background, decoupled from Prometheus scrapes.
+type Job struct {
+	// Name identifies the job, and is exposed as the probe label of the
+	// scheduler metrics.
+	Name string
+	// Interval is the time between the start of two consecutive runs.
+	Interval time.Duration
+	// Run executes the probes of the job. Runs of the same job never overlap.
+	Run func(ctx context.Context)
+}
+
+// Prober is implemented by collectors whose probes are run by the Scheduler.
+type Prober interface {
+	Jobs() []Job
+}
+
+// Config provides the necessary configuration for creating a Scheduler.
+type Config struct {
+	Logger micrologger.Logger
+
+	Probers []Prober
+}
+
+// Scheduler runs the jobs of all configured Probers on their own interval,
+// and implements the Collector interface, exposing when and for how long
+// each job last ran.
+type Scheduler struct {
+	logger micrologger.Logger
+
+	probers []Prober
+
+	lastRunDesc  *prometheus.Desc
+	durationDesc *prometheus.Desc
+
+	// ctx is the context given to Run, and is nil until the Scheduler runs.
+	ctx context.Context
+	// workers holds the running jobs, keyed by job name.
+	workers map[string]worker
+	// stopped holds the done channels of stopped workers, keyed by job name,
+	// until they returned, so that a restarted job waits for the run of its
+	// previous worker which may still be in flight.
+	stopped map[string]chan struct{}
+	// runs holds the result of the last run of each job, keyed by job name.
+	runs  map[string]run
+	mutex sync.Mutex
+}
+
+type worker struct {
+	interval time.Duration
+	cancel   context.CancelFunc
+	// done is closed once the loop of the worker returned.
+	done chan struct{}
+}
+
+type run struct {
+	start    time.Time
+	duration time.Duration
+}
+
+// New creates a Scheduler, given a Config.
+func New(config Config) (*Scheduler, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if len(config.Probers) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Probers must not be empty", config)
+	}
+
+	for _, p := range config.Probers {
+		for _, j := range p.Jobs() {
+			err := validateJob(j)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+	}
+
+	s := &Scheduler{
+		logger: config.Logger,
+
+		probers: config.Probers,
+
+		lastRunDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "probe_last_run_timestamp_seconds"),
+			"Unix timestamp of the start of the last run of the probe.",
+			[]string{"probe"},
+			nil,
+		),
+		durationDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "probe_duration_seconds"),
+			"Duration of the last run of the probe.",
+			[]string{"probe"},
+			nil,
+		),
+
+		workers: map[string]worker{},
+		stopped: map[string]chan struct{}{},
+		runs:    map[string]run{},
+	}
+
+	return s, nil
+}
+
+// Run starts all jobs and blocks until the given context is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mutex.Lock()
+	s.ctx = ctx
+	s.mutex.Unlock()
+
+	err := s.Sync()
+	if err != nil {
+		s.logger.Log("level", "error", "message", "failed to start jobs", "stack", microerror.JSON(err))
+	}
+
+	<-ctx.Done()
+}
+
+// Sync reconciles the running jobs with the current jobs of all Probers, to be
+// called after Probers have been reconfigured. New jobs are started, jobs
+// which are gone are stopped and jobs whose interval changed are restarted.
+// Other jobs keep running on their schedule.
+func (s *Scheduler) Sync() error {
+	desired := map[string]Job{}
+	for _, p := range s.probers {
+		for _, j := range p.Jobs() {
+			err := validateJob(j)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			desired[j.Name] = j
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.ctx == nil {
+		return nil
+	}
+
+	for name, w := range s.workers {
+		j, ok := desired[name]
+		if ok && j.Interval == w.interval {
+			continue
+		}
+
+		w.cancel()
+		delete(s.workers, name)
+		s.stopped[name] = w.done
+
+		if !ok {
+			delete(s.runs, name)
+		}
+	}
+
+	for name, j := range desired {
+		if _, ok := s.workers[name]; ok {
+			continue
+		}
+
+		previous := s.stopped[name]
+		delete(s.stopped, name)
+
+		ctx, cancel := context.WithCancel(s.ctx)
+		done := make(chan struct{})
+		s.workers[name] = worker{
+			interval: j.Interval,
+			cancel:   cancel,
+			done:     done,
+		}
+
+		go s.loop(ctx, j, previous, done)
+	}
+
+	return nil
+}
+
+// Describe implements the Describe method of the Collector interface.
+func (s *Scheduler) Describe(ch chan<- *prometheus.Desc) {
+	ch <- s.lastRunDesc
+	ch <- s.durationDesc
+}
+
+// Collect implements the Collect method of the Collector interface.
+func (s *Scheduler) Collect(ch chan<- prometheus.Metric) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for name, r := range s.runs {
+		ch <- prometheus.MustNewConstMetric(
+			s.lastRunDesc,
+			prometheus.GaugeValue,
+			float64(r.start.UnixNano())/float64(time.Second),
+			name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			s.durationDesc,
+			prometheus.GaugeValue,
+			r.duration.Seconds(),
+			name,
+		)
+	}
+}
+
+// loop runs the given job immediately, and then once per interval, until the
+// given context is cancelled. It first waits for the loop of the previous
+// worker of the job to return, if any, so that runs of the job never overlap,
+// and closes done once it returned.
+func (s *Scheduler) loop(ctx context.Context, j Job, previous <-chan struct{}, done chan struct{}) {
+	defer func() {
+		close(done)
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		// Nothing needs to wait for this worker anymore.
+		if s.stopped[j.Name] == done {
+			delete(s.stopped, j.Name)
+		}
+	}()
+
+	// The previous worker is cancelled, so its run in flight ends soon. It
+	// is waited for even if this worker is cancelled as well, since a later
+	// worker of the job only waits for this one.
+	if previous != nil {
+		<-previous
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	s.logger.Log("level", "debug", "message", "starting job", "job", j.Name, "interval", j.Interval.String())
+
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.runOnce(ctx, j)
+
+		select {
+		case <-ctx.Done():
+			s.logger.Log("level", "debug", "message", "stopped job", "job", j.Name)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(ctx context.Context, j Job) {
+	start := time.Now()
+	j.Run(ctx)
+	elapsed := time.Since(start)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// The job may have been stopped by Sync while it was running, in which
+	// case its results must not be recorded anymore.
+	if ctx.Err() != nil {
+		return
+	}
+
+	s.runs[j.Name] = run{
+		start:    start,
+		duration: elapsed,
+	}
+}
+
+func validateJob(j Job) error {
+	if j.Name == "" {
+		return microerror.Maskf(invalidConfigError, "%T.Name must not be empty", j)
+	}
+	if j.Interval <= 0 {
+		return microerror.Maskf(invalidConfigError, "%T.Interval of job %#q must be greater than zero", j, j.Name)
+	}
+	if j.Run == nil {
+		return microerror.Maskf(invalidConfigError, "%T.Run of job %#q must not be empty", j, j.Name)
+	}
+
+	return nil
+}
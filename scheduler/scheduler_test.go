@@ -0,0 +1,402 @@
+package scheduler
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type testProber struct {
+	jobs []Job
+}
+
+func (p *testProber) Jobs() []Job {
+	return p.jobs
+}
+
+func testJob(name string, interval time.Duration) Job {
+	return Job{
+		Name:     name,
+		Interval: interval,
+		Run:      func(ctx context.Context) {},
+	}
+}
+
+func Test_New(t *testing.T) {
+	testCases := []struct {
+		name         string
+		inputJobs    []Job
+		errorMatcher func(error) bool
+	}{
+		{
+			name:         "case 0: valid jobs",
+			inputJobs:    []Job{testJob("a", time.Minute)},
+			errorMatcher: nil,
+		},
+		{
+			name:         "case 1: job without name",
+			inputJobs:    []Job{testJob("", time.Minute)},
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name:         "case 2: job without interval",
+			inputJobs:    []Job{testJob("a", 0)},
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name:         "case 3: job without run function",
+			inputJobs:    []Job{{Name: "a", Interval: time.Minute}},
+			errorMatcher: IsInvalidConfig,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			_, err := New(Config{
+				Logger:  microloggertest.New(),
+				Probers: []Prober{&testProber{jobs: tc.inputJobs}},
+			})
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
+
+func Test_Run(t *testing.T) {
+	runs := make(chan struct{}, 10)
+
+	prober := &testProber{
+		jobs: []Job{
+			{
+				Name:     "a",
+				Interval: 10 * time.Millisecond,
+				Run: func(ctx context.Context) {
+					select {
+					case runs <- struct{}{}:
+					default:
+					}
+				},
+			},
+		},
+	}
+
+	s, err := New(Config{
+		Logger:  microloggertest.New(),
+		Probers: []Prober{prober},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	returned := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(returned)
+	}()
+
+	// The job runs immediately, and then once per interval.
+	for range 2 {
+		select {
+		case <-runs:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("job did not run")
+		}
+	}
+
+	s.mutex.Lock()
+	done := s.workers["a"].done
+	s.mutex.Unlock()
+
+	cancel()
+
+	select {
+	case <-returned:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Run did not return")
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("job did not stop")
+	}
+}
+
+func Test_Sync(t *testing.T) {
+	prober := &testProber{
+		jobs: []Job{
+			testJob("a", time.Hour),
+			testJob("b", time.Hour),
+		},
+	}
+
+	s, err := New(Config{
+		Logger:  microloggertest.New(),
+		Probers: []Prober{prober},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s.mutex.Lock()
+	s.ctx = ctx
+	s.mutex.Unlock()
+
+	err = s.Sync()
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if !cmp.Equal(s.workerNames(), []string{"a", "b"}) {
+		t.Fatalf("\n\n%s\n", cmp.Diff([]string{"a", "b"}, s.workerNames()))
+	}
+
+	prober.jobs = []Job{
+		testJob("b", time.Hour),
+		testJob("c", time.Hour),
+	}
+
+	err = s.Sync()
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if !cmp.Equal(s.workerNames(), []string{"b", "c"}) {
+		t.Fatalf("\n\n%s\n", cmp.Diff([]string{"b", "c"}, s.workerNames()))
+	}
+
+	prober.jobs = []Job{
+		testJob("c", 0),
+	}
+
+	err = s.Sync()
+	if !IsInvalidConfig(err) {
+		t.Fatalf("error == %#v, want invalidConfigError", err)
+	}
+	if !cmp.Equal(s.workerNames(), []string{"b", "c"}) {
+		t.Fatalf("\n\n%s\n", cmp.Diff([]string{"b", "c"}, s.workerNames()))
+	}
+}
+
+func Test_Sync_runsDoNotOverlap(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	started := make(chan struct{}, 10)
+
+	// slowJob ignores the cancellation of its context, like a probe blocked
+	// in a dial.
+	slowJob := func(interval time.Duration) Job {
+		return Job{
+			Name:     "a",
+			Interval: interval,
+			Run: func(ctx context.Context) {
+				n := running.Add(1)
+				for {
+					m := maxRunning.Load()
+					if n <= m || maxRunning.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				started <- struct{}{}
+
+				time.Sleep(100 * time.Millisecond)
+				running.Add(-1)
+			},
+		}
+	}
+
+	prober := &testProber{
+		jobs: []Job{slowJob(time.Hour)},
+	}
+
+	s, err := New(Config{
+		Logger:  microloggertest.New(),
+		Probers: []Prober{prober},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s.mutex.Lock()
+	s.ctx = ctx
+	s.mutex.Unlock()
+
+	err = s.Sync()
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	<-started
+
+	// Restart the job twice while its first run is in flight.
+	for _, interval := range []time.Duration{2 * time.Hour, 3 * time.Hour} {
+		prober.jobs = []Job{slowJob(interval)}
+
+		err = s.Sync()
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+	}
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("restarted job did not run")
+	}
+
+	if maxRunning.Load() != 1 {
+		t.Fatalf("max concurrent runs == %d, want 1", maxRunning.Load())
+	}
+}
+
+func Test_Sync_intervalChange(t *testing.T) {
+	prober := &testProber{
+		jobs: []Job{testJob("a", time.Hour)},
+	}
+
+	s, err := New(Config{
+		Logger:  microloggertest.New(),
+		Probers: []Prober{prober},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s.mutex.Lock()
+	s.ctx = ctx
+	s.mutex.Unlock()
+
+	err = s.Sync()
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	s.mutex.Lock()
+	previous := s.workers["a"]
+	s.mutex.Unlock()
+
+	// An unchanged job keeps its worker.
+	err = s.Sync()
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	s.mutex.Lock()
+	current := s.workers["a"]
+	s.mutex.Unlock()
+
+	if current.done != previous.done {
+		t.Fatalf("unchanged job was restarted")
+	}
+
+	// A job whose interval changed is restarted.
+	prober.jobs = []Job{testJob("a", 2*time.Hour)}
+
+	err = s.Sync()
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	s.mutex.Lock()
+	current = s.workers["a"]
+	s.mutex.Unlock()
+
+	if current.done == previous.done {
+		t.Fatalf("job with changed interval was not restarted")
+	}
+	if current.interval != 2*time.Hour {
+		t.Fatalf("interval == %v, want %v", current.interval, 2*time.Hour)
+	}
+
+	select {
+	case <-previous.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("previous worker did not stop")
+	}
+}
+
+func Test_runOnce(t *testing.T) {
+	prober := &testProber{
+		jobs: []Job{
+			{
+				Name:     "a",
+				Interval: time.Hour,
+				Run: func(ctx context.Context) {
+					time.Sleep(10 * time.Millisecond)
+				},
+			},
+		},
+	}
+
+	s, err := New(Config{
+		Logger:  microloggertest.New(),
+		Probers: []Prober{prober},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	if n := testutil.CollectAndCount(s); n != 0 {
+		t.Fatalf("metrics == %d, want 0", n)
+	}
+
+	before := time.Now()
+	s.runOnce(context.Background(), prober.jobs[0])
+
+	r := s.runs["a"]
+	if r.start.Before(before) || r.start.After(time.Now()) {
+		t.Fatalf("start == %v, want between %v and now", r.start, before)
+	}
+	if r.duration < 10*time.Millisecond {
+		t.Fatalf("duration == %v, want at least %v", r.duration, 10*time.Millisecond)
+	}
+
+	if n := testutil.CollectAndCount(s, "scheduler_probe_last_run_timestamp_seconds", "scheduler_probe_duration_seconds"); n != 2 {
+		t.Fatalf("metrics == %d, want 2", n)
+	}
+
+	// Runs of stopped jobs are not recorded.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s.runOnce(ctx, prober.jobs[0])
+
+	if s.runs["a"] != r {
+		t.Fatalf("run == %v, want %v", s.runs["a"], r)
+	}
+}
+
+func (s *Scheduler) workerNames() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var names []string
+	for name := range s.workers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}